@@ -6,8 +6,11 @@ import (
 	"dou-survey/app/service"
 	"dou-survey/internal/logger"
 	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/asaskevich/govalidator"
@@ -19,15 +22,62 @@ type SurveyControllerInterface interface {
 	GetConfirmed(c *gin.Context)
 	Confirm(c *gin.Context)
 	ChoiceVoters(c *gin.Context)
+	Crosstab(c *gin.Context)
+	Series(c *gin.Context)
+	Votes(c *gin.Context)
+	BallotLog(c *gin.Context)
+	CheckReceipt(c *gin.Context)
 	Vote(c *gin.Context)
 	ListWaitingConfirmation(c *gin.Context)
 	ListActive(c *gin.Context)
+	ListVotable(c *gin.Context)
+	CountVotable(c *gin.Context)
 	ListResults(c *gin.Context)
 	CountWaitingConfirmation(c *gin.Context)
+	SummarizeWaitingConfirmation(c *gin.Context)
+	ReconcileTallies(c *gin.Context)
+	Claim(c *gin.Context)
+	Release(c *gin.Context)
+	MarkSensitive(c *gin.Context)
 	CountActive(c *gin.Context)
 	CountResults(c *gin.Context)
 	Info(c *gin.Context)
 	Create(c *gin.Context)
+	Owned(c *gin.Context)
+	Update(c *gin.Context)
+	Replace(c *gin.Context)
+	Remove(c *gin.Context)
+	Submit(c *gin.Context)
+	History(c *gin.Context)
+	Moderations(c *gin.Context)
+	MyBallot(c *gin.Context)
+	Quotas(c *gin.Context)
+	CreateInvitations(c *gin.Context)
+	ListInvitations(c *gin.Context)
+	RevokeInvitation(c *gin.Context)
+	SaveDraft(c *gin.Context)
+	Draft(c *gin.Context)
+	SubmitDraft(c *gin.Context)
+	ExportResults(c *gin.Context)
+	ExportResponses(c *gin.Context)
+	ExportOwnedResults(c *gin.Context)
+	ExportOwnedResponses(c *gin.Context)
+	ExportCodedResponses(c *gin.Context)
+	ExportOwnedCodedResponses(c *gin.Context)
+	Codebook(c *gin.Context)
+	OwnedCodebook(c *gin.Context)
+	Import(c *gin.Context)
+	ExportDefinition(c *gin.Context)
+	Clone(c *gin.Context)
+	LinkWave(c *gin.Context)
+	UnlinkWave(c *gin.Context)
+	SaveTemplate(c *gin.Context)
+	PublishTemplate(c *gin.Context)
+	ListTemplates(c *gin.Context)
+	Template(c *gin.Context)
+	UseTemplate(c *gin.Context)
+	DeleteTemplate(c *gin.Context)
+	UnpublishTemplate(c *gin.Context)
 }
 
 // SurveyController handles communication with the survey service
@@ -59,19 +109,119 @@ func (uc *SurveyController) ChoiceVoters(c *gin.Context) {
 
 	choiceIDInt := uint(choiceIDInt64)
 
-	voters, err := uc.service.ChoiceVotersInfo(choiceIDInt)
+	demographics, err := uc.service.ChoiceVotersInfo(choiceIDInt)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, appError.StatusCode(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, demographics)
+}
+
+// Crosstab breaks the results of a finished survey down by voter demographics
+func (uc *SurveyController) Crosstab(c *gin.Context) {
+	surveyID, err := strconv.ParseUint(c.Param("survey"), 10, 32)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	crosstab, err := uc.service.Crosstab(uint(surveyID))
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, appError.StatusCode(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, crosstab)
+}
+
+// Series compares the waves of a recurring survey up to the given one, optionally
+// within a demographic dimension (?dimension=IsResident) or one of its groups (&group=true)
+func (uc *SurveyController) Series(c *gin.Context) {
+	surveyID, err := strconv.ParseUint(c.Param("survey"), 10, 32)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	dimension := model.CrosstabDimension(c.DefaultQuery("dimension", string(model.CrosstabDimensionTotal)))
+	group := c.Query("group")
+	if err := model.ValidateSeriesFilter(dimension, group); err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	series, err := uc.service.SeriesResults(uint(surveyID), dimension, group)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, appError.StatusCode(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, series)
+}
+
+// Votes returns the results of a finished survey with its individual votes, results
+// elsewhere only carry the vote counts
+func (uc *SurveyController) Votes(c *gin.Context) {
+	surveyID, err := strconv.ParseUint(c.Param("survey"), 10, 32)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	survey, err := uc.service.FindByIDWithVotes(uint(surveyID))
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, appError.StatusCode(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, survey)
+}
+
+// BallotLog returns the ballot log of a finished survey for offline verification
+func (uc *SurveyController) BallotLog(c *gin.Context) {
+	surveyID, err := strconv.ParseUint(c.Param("survey"), 10, 32)
 	if err != nil {
 		uc.logger.Error(err.Error())
 		appError.Respond(c, http.StatusBadRequest, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, voters)
+	log, err := uc.service.BallotLog(uint(surveyID))
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, appError.StatusCode(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, log)
+}
+
+// CheckReceipt tells whether the ballot of a receipt is in the ballot log
+func (uc *SurveyController) CheckReceipt(c *gin.Context) {
+	check, err := uc.service.CheckReceipt(c.Param("receipt"))
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, appError.StatusCode(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, check)
 }
 
 type UpdateConfirmStatusBody struct {
 	SurveyID uint                `binding:"required"`
 	Status   model.ConfirmStatus `binding:"required"`
+	Reason   model.DeclineReason // required when declining
+	Comment  string
 }
 
 // Find implements the method to handle the service to find a survey by the primary key
@@ -84,10 +234,13 @@ func (uc *SurveyController) Confirm(c *gin.Context) {
 		return
 	}
 
-	err := uc.service.UpdateConfirmStatus(requestBody.SurveyID, requestBody.Status)
+	// Employee was added to context in middleware
+	employee := c.MustGet("employee").(*model.Employee)
+
+	err := uc.service.UpdateConfirmStatus(employee, requestBody.SurveyID, requestBody.Status, requestBody.Reason, requestBody.Comment)
 	if err != nil {
 		uc.logger.Error(err.Error())
-		appError.Respond(c, http.StatusBadRequest, err)
+		appError.Respond(c, appError.StatusCode(err), err)
 		return
 	}
 
@@ -95,8 +248,15 @@ func (uc *SurveyController) Confirm(c *gin.Context) {
 }
 
 type VoteRequestBody struct {
-	SurveyID uint   `binding:"required"`
-	Votes    []uint `binding:"required"`
+	SurveyID uint           `binding:"required"`
+	Answers  []model.Answer `binding:"required,dive"`
+	Token    string         // invitation token, required by private surveys
+}
+
+// VoteResponse carries the created votes and the receipt to check them in the ballot log
+type VoteResponse struct {
+	Votes   []model.Vote
+	Receipt string
 }
 
 // Find implements the method to handle the service to find a survey by the primary key
@@ -117,48 +277,146 @@ func (uc *SurveyController) Vote(c *gin.Context) {
 		return
 	}
 
-	// check vote count matches
-	count, err := uc.service.CountQuestion(requestBody.SurveyID)
+	// submit vote, answers are checked against question types and display conditions
+	created, receipt, err := uc.service.Vote(userFull.ID, requestBody.SurveyID, requestBody.Answers, requestBody.Token)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, appError.StatusCode(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, VoteResponse{created, receipt})
+}
+
+// Find implements the method to handle the service to find a survey by the primary key
+func (uc *SurveyController) CountWaitingConfirmation(c *gin.Context) {
+	result, err := uc.service.CountWaitingConfirmation()
 	if err != nil {
 		uc.logger.Error(err.Error())
 		appError.Respond(c, http.StatusBadRequest, err)
 		return
 	}
-	if count != len(requestBody.Votes) {
-		err := errors.New("vote count does not match")
+
+	c.JSON(http.StatusOK, result)
+}
+
+// SummarizeWaitingConfirmation shows how many waiting surveys are claimed or overdue
+func (uc *SurveyController) SummarizeWaitingConfirmation(c *gin.Context) {
+	result, err := uc.service.SummarizeWaitingConfirmation()
+	if err != nil {
 		uc.logger.Error(err.Error())
 		appError.Respond(c, http.StatusBadRequest, err)
 		return
 	}
 
-	// check voted before
-	votedAlready, err := uc.service.VotedAlready(userFull.ID, requestBody.SurveyID)
+	c.JSON(http.StatusOK, result)
+}
+
+type ReconcileTalliesResponse struct {
+	Drift []model.TallyDrift // tallies that were repaired
+}
+
+// ReconcileTallies recounts the result tallies and reports the ones that drifted from the votes
+func (uc *SurveyController) ReconcileTallies(c *gin.Context) {
+	drift, err := uc.service.ReconcileTallies()
 	if err != nil {
 		uc.logger.Error(err.Error())
 		appError.Respond(c, http.StatusBadRequest, err)
 		return
 	}
-	if votedAlready {
-		err := errors.New("vote submitted already for this survey")
+	if len(drift) > 0 {
+		uc.logger.Warnf("repaired %d drifted tallies", len(drift))
+	}
+
+	c.JSON(http.StatusOK, ReconcileTalliesResponse{Drift: drift})
+}
+
+type ClaimSurveyBody struct {
+	SurveyID uint `binding:"required"`
+}
+
+type ClaimSurveyResponse struct {
+	SurveyID     uint
+	ClaimedUntil time.Time
+}
+
+// Claim locks a waiting survey to the employee while they review it
+func (uc *SurveyController) Claim(c *gin.Context) {
+	var requestBody ClaimSurveyBody
+
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	// Employee was added to context in middleware
+	employee := c.MustGet("employee").(*model.Employee)
+
+	claimedUntil, err := uc.service.Claim(employee, requestBody.SurveyID)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, appError.StatusCode(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, ClaimSurveyResponse{requestBody.SurveyID, claimedUntil})
+}
+
+// Release gives a claimed survey back to the review queue
+func (uc *SurveyController) Release(c *gin.Context) {
+	var requestBody ClaimSurveyBody
+
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
 		uc.logger.Error(err.Error())
 		appError.Respond(c, http.StatusBadRequest, err)
 		return
 	}
 
-	// submit vote
-	created, err := uc.service.Vote(userFull.ID, requestBody.SurveyID, requestBody.Votes)
+	// Employee was added to context in middleware
+	employee := c.MustGet("employee").(*model.Employee)
+
+	err := uc.service.Release(employee, requestBody.SurveyID)
 	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, appError.StatusCode(err), err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+type SensitiveSurveyBody struct {
+	SurveyID  uint  `binding:"required"`
+	Sensitive *bool `binding:"required"`
+}
+
+// MarkSensitive flags a waiting survey as a sensitive topic that needs a senior approval
+func (uc *SurveyController) MarkSensitive(c *gin.Context) {
+	var requestBody SensitiveSurveyBody
+
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
 		uc.logger.Error(err.Error())
 		appError.Respond(c, http.StatusBadRequest, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, created)
+	// Employee was added to context in middleware
+	employee := c.MustGet("employee").(*model.Employee)
+
+	err := uc.service.MarkSensitive(employee, requestBody.SurveyID, *requestBody.Sensitive)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, appError.StatusCode(err), err)
+		return
+	}
+
+	c.Status(http.StatusOK)
 }
 
 // Find implements the method to handle the service to find a survey by the primary key
-func (uc *SurveyController) CountWaitingConfirmation(c *gin.Context) {
-	result, err := uc.service.CountWaitingConfirmation()
+func (uc *SurveyController) CountActive(c *gin.Context) {
+	result, err := uc.service.CountActive(nil)
 	if err != nil {
 		uc.logger.Error(err.Error())
 		appError.Respond(c, http.StatusBadRequest, err)
@@ -168,9 +426,17 @@ func (uc *SurveyController) CountWaitingConfirmation(c *gin.Context) {
 	c.JSON(http.StatusOK, result)
 }
 
-// Find implements the method to handle the service to find a survey by the primary key
-func (uc *SurveyController) CountActive(c *gin.Context) {
-	result, err := uc.service.CountActive()
+// CountVotable counts the active surveys the authenticated user can vote in
+func (uc *SurveyController) CountVotable(c *gin.Context) {
+	user := c.MustGet("user").(*model.UserReduced)
+	userFull, err := uc.userService.FindByIdNumber(user.IDNumber)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	result, err := uc.service.CountActive(userFull)
 	if err != nil {
 		uc.logger.Error(err.Error())
 		appError.Respond(c, http.StatusBadRequest, err)
@@ -214,7 +480,10 @@ func (uc *SurveyController) ListWaitingConfirmation(c *gin.Context) {
 	limitInt := uint(limitInt64)
 	offsetInt := uint(offsetInt64)
 
-	result, err := uc.service.ListWaitingConfirmation(limitInt, offsetInt)
+	// Employee was added to context in middleware
+	employee := c.MustGet("employee").(*model.Employee)
+
+	result, err := uc.service.ListWaitingConfirmation(employee.UserRefer, limitInt, offsetInt)
 	if err != nil {
 		uc.logger.Error(err.Error())
 		appError.Respond(c, http.StatusBadRequest, err)
@@ -248,7 +517,7 @@ func (uc *SurveyController) ListActive(c *gin.Context) {
 	limitInt := uint(limitInt64)
 	offsetInt := uint(offsetInt64)
 
-	result, err := uc.service.ListActive(limitInt, offsetInt)
+	result, err := uc.service.ListActive(limitInt, offsetInt, nil)
 	if err != nil {
 		uc.logger.Error(err.Error())
 		appError.Respond(c, http.StatusBadRequest, err)
@@ -260,6 +529,43 @@ func (uc *SurveyController) ListActive(c *gin.Context) {
 	c.JSON(http.StatusOK, result)
 }
 
+// ListVotable lists the active surveys the authenticated user can vote in
+func (uc *SurveyController) ListVotable(c *gin.Context) {
+	limit := c.DefaultQuery("limit", "5")
+	offset := c.DefaultQuery("offset", "0")
+
+	limitInt64, err := strconv.ParseUint(limit, 10, 32)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	offsetInt64, err := strconv.ParseUint(offset, 10, 32)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	user := c.MustGet("user").(*model.UserReduced)
+	userFull, err := uc.userService.FindByIdNumber(user.IDNumber)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	result, err := uc.service.ListActive(uint(limitInt64), uint(offsetInt64), userFull)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
 // Find implements the method to handle the service to find a survey by the primary key
 func (uc *SurveyController) ListResults(c *gin.Context) {
 	limit := c.DefaultQuery("limit", "5")
@@ -316,6 +622,11 @@ func (uc *SurveyController) GetConfirmed(c *gin.Context) {
 	c.JSON(http.StatusOK, confirmed)
 }
 
+type SurveyInfoResponse struct {
+	*model.PublicSurvey
+	Status model.SurveyStatus
+}
+
 // Find implements the method to handle the service to find a survey by the primary key
 func (uc *SurveyController) Info(c *gin.Context) {
 	surveyID := c.Param("survey")
@@ -343,38 +654,29 @@ func (uc *SurveyController) Info(c *gin.Context) {
 		return
 	}
 
-	now := time.Now().UTC()
-
-	// uc.logger.Info(now)
-	// uc.logger.Info(survey.DateStart)
-	// uc.logger.Info(survey.DateEnd)
-	// uc.logger.Info(now.After(survey.DateStart))
-	// uc.logger.Info(now.Before(survey.DateEnd))
+	status := survey.Status(time.Now().UTC())
 
-	if !(now.After(survey.DateStart) && now.Before(survey.DateEnd)) { // if survey is inactive, you cant get votes
-		// now is before start or now is after end
-		// so survey is not active but why?
-		if now.After(survey.DateStart) {
-			// now is after start which means now is also after end, voting ended, survey completed
-			survey, err = uc.service.FindByIDWithVotes(surveyIDInt)
+	switch status {
+	case model.SurveyStatusClosed, model.SurveyStatusArchived:
+		// voting ended, survey completed
+		survey, err = uc.service.FindByIDWithResults(surveyIDInt)
+		if err != nil {
+			uc.logger.Error(err.Error())
+			appError.Respond(c, http.StatusBadRequest, err)
+			return
+		}
+	case model.SurveyStatusActive:
+		if withChoices { // if survey is active, do you want choices
+			survey, err = uc.service.FindByIDWithoutVotes(surveyIDInt)
 			if err != nil {
 				uc.logger.Error(err.Error())
 				appError.Respond(c, http.StatusBadRequest, err)
 				return
 			}
-		} // else if now.Before(survey.DateEnd) {
-		// 	// now is before end which means now is also before start, survey voting havent started
-		// }
-	} else if withChoices { // if survey is active, do you want choices
-		survey, err = uc.service.FindByIDWithoutVotes(surveyIDInt)
-		if err != nil {
-			uc.logger.Error(err.Error())
-			appError.Respond(c, http.StatusBadRequest, err)
-			return
 		}
-	}
+	} // else survey voting havent started, only reduced info
 
-	c.JSON(http.StatusOK, survey)
+	c.JSON(http.StatusOK, SurveyInfoResponse{PublicSurvey: model.NewPublicSurvey(survey), Status: status})
 }
 
 // Find implements the method to handle the service to find a survey by the primary key
@@ -424,3 +726,997 @@ func (uc *SurveyController) Create(c *gin.Context) {
 
 	c.JSON(http.StatusOK, created)
 }
+
+// authUserID returns the id of the user added to context in auth middleware
+func (uc *SurveyController) authUserID(c *gin.Context) (userID uint, err error) {
+	user := c.MustGet("user").(*model.UserReduced)
+	userFull, err := uc.userService.FindByIdNumber(user.IDNumber)
+	if err != nil {
+		return 0, err
+	}
+
+	return userFull.ID, nil
+}
+
+// Import creates a draft survey from a json or yaml survey definition document, yaml
+// is read with format=yaml or a yaml content type
+func (uc *SurveyController) Import(c *gin.Context) {
+	format := model.DefinitionFormat(c.DefaultQuery("format", string(model.DefinitionFormatJSON)))
+	if strings.Contains(c.ContentType(), "yaml") {
+		format = model.DefinitionFormatYAML
+	}
+
+	data, err := c.GetRawData()
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	definition, err := model.ParseSurveyDefinition(data, format)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	userID, err := uc.authUserID(c)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	created, err := uc.service.Import(userID, definition)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, created)
+}
+
+// ExportDefinition downloads the survey definition document of a survey to its owner,
+// as json or with format=yaml as yaml
+func (uc *SurveyController) ExportDefinition(c *gin.Context) {
+	surveyID, err := strconv.ParseUint(c.Param("survey"), 10, 32)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	userID, err := uc.authUserID(c)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	definition, err := uc.service.ExportDefinition(userID, uint(surveyID))
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, appError.StatusCode(err), err)
+		return
+	}
+
+	format := model.DefinitionFormat(c.DefaultQuery("format", string(model.DefinitionFormatJSON)))
+	data, err := definition.Encode(format)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	contentType := "application/json; charset=utf-8"
+	if format == model.DefinitionFormatYAML {
+		contentType = "application/x-yaml; charset=utf-8"
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("survey-%d.%s", surveyID, format)))
+	c.Data(http.StatusOK, contentType, data)
+}
+
+// Owned returns a survey with its questions to its owner, whatever its status
+func (uc *SurveyController) Owned(c *gin.Context) {
+	surveyID, err := strconv.ParseUint(c.Param("survey"), 10, 32)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	userID, err := uc.authUserID(c)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	survey, err := uc.service.FindOwned(userID, uint(surveyID))
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, appError.StatusCode(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, survey)
+}
+
+type UpdateSurveyBody struct {
+	Subject     string
+	Description string
+	DateStart   time.Time
+	DateEnd     time.Time
+	MinCellSize uint
+}
+
+// Update changes the given fields of a survey that is not confirmed yet
+func (uc *SurveyController) Update(c *gin.Context) {
+	var requestBody UpdateSurveyBody
+
+	surveyID, err := strconv.ParseUint(c.Param("survey"), 10, 32)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	userID, err := uc.authUserID(c)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	update := model.Survey{
+		Subject:     requestBody.Subject,
+		Description: requestBody.Description,
+		DateStart:   requestBody.DateStart,
+		DateEnd:     requestBody.DateEnd,
+		MinCellSize: requestBody.MinCellSize,
+	}
+
+	err = uc.service.Update(userID, uint(surveyID), update)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, appError.StatusCode(err), err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// Replace overwrites a survey that is not confirmed yet, questions and choices included
+func (uc *SurveyController) Replace(c *gin.Context) {
+	var requestBody *model.Survey
+
+	surveyID, err := strconv.ParseUint(c.Param("survey"), 10, 32)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	userID, err := uc.authUserID(c)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+	requestBody.UserRefer = userID
+
+	valid, err := govalidator.ValidateStruct(requestBody)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+	if !valid {
+		err := errors.New("fields are not valid")
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	replaced, err := uc.service.Replace(userID, uint(surveyID), requestBody)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, appError.StatusCode(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, replaced)
+}
+
+// Remove deletes a survey that is not confirmed yet
+func (uc *SurveyController) Remove(c *gin.Context) {
+	surveyID, err := strconv.ParseUint(c.Param("survey"), 10, 32)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	userID, err := uc.authUserID(c)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	err = uc.service.Remove(userID, uint(surveyID))
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, appError.StatusCode(err), err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+type SubmitSurveyBody struct {
+	SurveyID uint `binding:"required"`
+}
+
+// Submit sends a draft survey for confirmation by an employee
+func (uc *SurveyController) Submit(c *gin.Context) {
+	var requestBody SubmitSurveyBody
+
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	userID, err := uc.authUserID(c)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	err = uc.service.Submit(userID, requestBody.SurveyID)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, appError.StatusCode(err), err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// History lists the recorded status changes of a survey
+func (uc *SurveyController) History(c *gin.Context) {
+	surveyID, err := strconv.ParseUint(c.Param("survey"), 10, 32)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	transitions, err := uc.service.ListTransitions(uint(surveyID))
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, transitions)
+}
+
+// Moderations shows the owner why their survey was confirmed or declined
+func (uc *SurveyController) Moderations(c *gin.Context) {
+	surveyID, err := strconv.ParseUint(c.Param("survey"), 10, 32)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	userID, err := uc.authUserID(c)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	moderations, err := uc.service.ListModerations(userID, uint(surveyID))
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, appError.StatusCode(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, moderations)
+}
+
+// MyBallot returns the current answers of the authenticated user to a survey
+func (uc *SurveyController) MyBallot(c *gin.Context) {
+	surveyID, err := strconv.ParseUint(c.Param("survey"), 10, 32)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	userID, err := uc.authUserID(c)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	ballot, err := uc.service.MyBallot(userID, uint(surveyID))
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, appError.StatusCode(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, ballot)
+}
+
+// Quotas returns the fill status of the survey quotas to its owner
+func (uc *SurveyController) Quotas(c *gin.Context) {
+	surveyID, err := strconv.ParseUint(c.Param("survey"), 10, 32)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	userID, err := uc.authUserID(c)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	status, err := uc.service.QuotaStatus(userID, uint(surveyID))
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, appError.StatusCode(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// CreateInvitations creates a batch of invitation tokens to a private survey of the owner
+func (uc *SurveyController) CreateInvitations(c *gin.Context) {
+	surveyID, err := strconv.ParseUint(c.Param("survey"), 10, 32)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	var requestBody model.InvitationRequest
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	userID, err := uc.authUserID(c)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	invitations, err := uc.service.CreateInvitations(userID, uint(surveyID), requestBody)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, appError.StatusCode(err), err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, invitations)
+}
+
+// ListInvitations returns the invitations of a survey with their redemptions to its owner
+func (uc *SurveyController) ListInvitations(c *gin.Context) {
+	surveyID, err := strconv.ParseUint(c.Param("survey"), 10, 32)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	userID, err := uc.authUserID(c)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	invitations, err := uc.service.ListInvitations(userID, uint(surveyID))
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, appError.StatusCode(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, invitations)
+}
+
+// RevokeInvitation stops an invitation of the owner from admitting more voters
+func (uc *SurveyController) RevokeInvitation(c *gin.Context) {
+	surveyID, err := strconv.ParseUint(c.Param("survey"), 10, 32)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	invitationID, err := strconv.ParseUint(c.Param("invitation"), 10, 32)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	userID, err := uc.authUserID(c)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if err = uc.service.RevokeInvitation(userID, uint(surveyID), uint(invitationID)); err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, appError.StatusCode(err), err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+type DraftRequestBody struct {
+	Answers []model.Answer `binding:"dive"`
+}
+
+// SaveDraft keeps the partial answers of the authenticated user to an active survey
+func (uc *SurveyController) SaveDraft(c *gin.Context) {
+	surveyID, err := strconv.ParseUint(c.Param("survey"), 10, 32)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	var requestBody DraftRequestBody
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	userID, err := uc.authUserID(c)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	draft, err := uc.service.SaveDraft(userID, uint(surveyID), requestBody.Answers)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, appError.StatusCode(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, draft)
+}
+
+// Draft returns the draft ballot of the authenticated user
+func (uc *SurveyController) Draft(c *gin.Context) {
+	surveyID, err := strconv.ParseUint(c.Param("survey"), 10, 32)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	userID, err := uc.authUserID(c)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	draft, err := uc.service.FindDraft(userID, uint(surveyID))
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, appError.StatusCode(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, draft)
+}
+
+type SubmitDraftRequestBody struct {
+	Token string // invitation token, required by private surveys
+}
+
+// SubmitDraft casts the draft ballot of the authenticated user as a vote
+func (uc *SurveyController) SubmitDraft(c *gin.Context) {
+	surveyID, err := strconv.ParseUint(c.Param("survey"), 10, 32)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	// the body is optional, only private surveys need it
+	var requestBody SubmitDraftRequestBody
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&requestBody); err != nil {
+			uc.logger.Error(err.Error())
+			appError.Respond(c, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	userID, err := uc.authUserID(c)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	created, receipt, err := uc.service.SubmitDraft(userID, uint(surveyID), requestBody.Token)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, appError.StatusCode(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, VoteResponse{created, receipt})
+}
+
+// exportFunc writes an export of a survey in a format
+type exportFunc func(surveyID uint, format model.ExportFormat, w io.Writer) error
+
+// exportWriter sends the download headers with the first bytes of an export, until
+// then errors can still be answered with a JSON error
+type exportWriter struct {
+	c        *gin.Context
+	format   model.ExportFormat
+	filename string
+	started  bool
+}
+
+func (w *exportWriter) Write(p []byte) (int, error) {
+	if !w.started {
+		w.started = true
+		w.c.Header("Content-Type", w.format.ContentType())
+		w.c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", w.filename+"."+string(w.format)))
+		w.c.Status(http.StatusOK)
+	}
+
+	return w.c.Writer.Write(p)
+}
+
+// ExportResults downloads the choice counts of a finished survey as csv, xlsx or ndjson
+func (uc *SurveyController) ExportResults(c *gin.Context) {
+	uc.export(c, "results", uc.service.ExportResults, false)
+}
+
+// ExportResponses downloads one row per respondent of a finished survey as csv, xlsx or ndjson
+func (uc *SurveyController) ExportResponses(c *gin.Context) {
+	uc.export(c, "responses", uc.service.ExportResponses, false)
+}
+
+// ExportCodedResponses downloads the responses of a finished survey coded after its codebook
+func (uc *SurveyController) ExportCodedResponses(c *gin.Context) {
+	uc.export(c, "coded", uc.service.ExportCodedResponses, false)
+}
+
+// ExportOwnedCodedResponses downloads the coded responses of a finished survey to its owner
+func (uc *SurveyController) ExportOwnedCodedResponses(c *gin.Context) {
+	uc.export(c, "coded", uc.service.ExportCodedResponses, true)
+}
+
+// Codebook returns the codebook of the coded responses as json, or as SPSS syntax
+// reading the coded csv export with format=sps
+func (uc *SurveyController) Codebook(c *gin.Context) {
+	uc.codebook(c, false)
+}
+
+// OwnedCodebook returns the codebook of the coded responses to the owner of the survey
+func (uc *SurveyController) OwnedCodebook(c *gin.Context) {
+	uc.codebook(c, true)
+}
+
+func (uc *SurveyController) codebook(c *gin.Context, owned bool) {
+	surveyID, err := strconv.ParseUint(c.Param("survey"), 10, 32)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "sps" {
+		err = errors.New("codebook format must be json or sps")
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if owned && !uc.checkOwner(c, uint(surveyID)) {
+		return
+	}
+
+	codebook, err := uc.service.Codebook(uint(surveyID))
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, appError.StatusCode(err), err)
+		return
+	}
+
+	if format == "sps" {
+		filename := fmt.Sprintf("survey-%d-coded", surveyID)
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename+".sps"))
+		c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(codebook.SPSSSyntax(filename+"."+string(model.ExportFormatCSV))))
+		return
+	}
+
+	c.JSON(http.StatusOK, codebook)
+}
+
+// checkOwner responds with an error unless the authenticated user owns the survey
+func (uc *SurveyController) checkOwner(c *gin.Context, surveyID uint) bool {
+	userID, err := uc.authUserID(c)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return false
+	}
+	if err = uc.service.CheckOwner(userID, surveyID); err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, appError.StatusCode(err), err)
+		return false
+	}
+
+	return true
+}
+
+// ExportOwnedResults downloads the choice counts of a finished survey to its owner
+func (uc *SurveyController) ExportOwnedResults(c *gin.Context) {
+	uc.export(c, "results", uc.service.ExportResults, true)
+}
+
+// ExportOwnedResponses downloads the responses of a finished survey to its owner
+func (uc *SurveyController) ExportOwnedResponses(c *gin.Context) {
+	uc.export(c, "responses", uc.service.ExportResponses, true)
+}
+
+// export streams an export of the survey in the format of the query, csv by default
+func (uc *SurveyController) export(c *gin.Context, name string, fn exportFunc, owned bool) {
+	surveyID, err := strconv.ParseUint(c.Param("survey"), 10, 32)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	format := model.ExportFormat(c.DefaultQuery("format", string(model.ExportFormatCSV)))
+	if err = format.Validate(); err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if owned && !uc.checkOwner(c, uint(surveyID)) {
+		return
+	}
+
+	w := &exportWriter{c: c, format: format, filename: fmt.Sprintf("survey-%d-%s", surveyID, name)}
+	if err = fn(uint(surveyID), format, w); err != nil {
+		uc.logger.Error(err.Error())
+		if w.started {
+			// the download is already under way
+			c.Abort()
+			return
+		}
+		appError.Respond(c, appError.StatusCode(err), err)
+		return
+	}
+
+	if !w.started {
+		// nothing to export still downloads an empty file
+		w.Write(nil)
+	}
+}
+
+// Clone copies the questions and settings of a survey of the owner into a new draft
+// with the dates of the request
+func (uc *SurveyController) Clone(c *gin.Context) {
+	surveyID, err := strconv.ParseUint(c.Param("survey"), 10, 32)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	var requestBody model.CloneRequest
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	userID, err := uc.authUserID(c)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	created, err := uc.service.Clone(userID, uint(surveyID), requestBody)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, appError.StatusCode(err), err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, created)
+}
+
+// LinkWave makes a survey of the owner the next wave of another of their surveys
+func (uc *SurveyController) LinkWave(c *gin.Context) {
+	surveyID, err := strconv.ParseUint(c.Param("survey"), 10, 32)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	var requestBody model.SeriesRequest
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	userID, err := uc.authUserID(c)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	err = uc.service.LinkWave(userID, uint(surveyID), requestBody.PreviousWave)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, appError.StatusCode(err), err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// UnlinkWave removes a survey of the owner from its series
+func (uc *SurveyController) UnlinkWave(c *gin.Context) {
+	surveyID, err := strconv.ParseUint(c.Param("survey"), 10, 32)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	userID, err := uc.authUserID(c)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	err = uc.service.UnlinkWave(userID, uint(surveyID))
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, appError.StatusCode(err), err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// SaveTemplate keeps a survey of the owner as a personal template
+func (uc *SurveyController) SaveTemplate(c *gin.Context) {
+	surveyID, err := strconv.ParseUint(c.Param("survey"), 10, 32)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	var requestBody model.TemplateRequest
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	userID, err := uc.authUserID(c)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	template, err := uc.service.SaveTemplate(userID, uint(surveyID), requestBody)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, appError.StatusCode(err), err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, template)
+}
+
+// PublishTemplate publishes a survey as an organization template listed to every user
+func (uc *SurveyController) PublishTemplate(c *gin.Context) {
+	surveyID, err := strconv.ParseUint(c.Param("survey"), 10, 32)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	var requestBody model.TemplateRequest
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	// Employee was added to context in middleware
+	employee := c.MustGet("employee").(*model.Employee)
+
+	template, err := uc.service.PublishTemplate(employee, uint(surveyID), requestBody)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, appError.StatusCode(err), err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, template)
+}
+
+// ListTemplates lists the organization templates and the personal templates of the user
+func (uc *SurveyController) ListTemplates(c *gin.Context) {
+	userID, err := uc.authUserID(c)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	templates, err := uc.service.ListTemplates(userID)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, appError.StatusCode(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, templates)
+}
+
+// Template returns a template with its survey definition
+func (uc *SurveyController) Template(c *gin.Context) {
+	templateID, err := strconv.ParseUint(c.Param("template"), 10, 32)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	userID, err := uc.authUserID(c)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	template, err := uc.service.FindTemplate(userID, uint(templateID))
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, appError.StatusCode(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+}
+
+// UseTemplate starts a new draft of the user from a template with the dates of the request
+func (uc *SurveyController) UseTemplate(c *gin.Context) {
+	templateID, err := strconv.ParseUint(c.Param("template"), 10, 32)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	var requestBody model.CloneRequest
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	userID, err := uc.authUserID(c)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	created, err := uc.service.UseTemplate(userID, uint(templateID), requestBody)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, appError.StatusCode(err), err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, created)
+}
+
+// DeleteTemplate removes a personal template of the user
+func (uc *SurveyController) DeleteTemplate(c *gin.Context) {
+	templateID, err := strconv.ParseUint(c.Param("template"), 10, 32)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	userID, err := uc.authUserID(c)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if err = uc.service.DeleteTemplate(userID, uint(templateID)); err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, appError.StatusCode(err), err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// UnpublishTemplate removes an organization template
+func (uc *SurveyController) UnpublishTemplate(c *gin.Context) {
+	templateID, err := strconv.ParseUint(c.Param("template"), 10, 32)
+	if err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if err = uc.service.UnpublishTemplate(uint(templateID)); err != nil {
+		uc.logger.Error(err.Error())
+		appError.Respond(c, appError.StatusCode(err), err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
@@ -2,6 +2,8 @@ package error
 
 import (
 	"errors"
+	"fmt"
+	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
@@ -12,10 +14,62 @@ var (
 	ErrNotFound = errors.New("not found")
 	// InvalidPaymentMethod error will be returned when payment method is not available
 	ErrInvalidPaymentMethod = errors.New("invalid payment method")
+	// ErrNotOwner error will be returned when a user changes a survey created by someone else
+	ErrNotOwner = errors.New("you are not the owner of this survey")
+	// ErrSurveyLocked error will be returned when a confirmed survey is edited
+	ErrSurveyLocked = errors.New("survey can not be edited after confirmation")
+	// ErrStatusChanged error will be returned when the survey status changed during a transition
+	ErrStatusChanged = errors.New("survey status was changed by someone else, try again")
+	// ErrSurveyClaimed error will be returned when a survey is reviewed while another employee claimed it
+	ErrSurveyClaimed = errors.New("survey is claimed by another reviewer")
+	// ErrPrivacyBudget error will be returned when publishing more results would exceed the privacy budget of a survey
+	ErrPrivacyBudget = errors.New("privacy budget of this survey is exhausted")
+	// ErrVotedAlready error will be returned when a user submits a second ballot to a survey
+	ErrVotedAlready = errors.New("vote submitted already for this survey")
+	// ErrAnonymousSurvey error will be returned when voter specific data of an anonymous survey is requested
+	ErrAnonymousSurvey = errors.New("ballots of this survey are anonymous")
+	// ErrNoisyResults error will be returned when individual votes are requested for a survey that only publishes noisy counts
+	ErrNoisyResults = errors.New("only noisy counts are published for this survey")
+	// ErrNotEligible error will be returned when a user votes in a survey whose eligibility criteria exclude them
+	ErrNotEligible = errors.New("you are not eligible to vote in this survey")
+	// ErrQuotaFull error will be returned when a ballot falls in a quota that reached its target
+	ErrQuotaFull = errors.New("the quota of this survey for your group is full")
+	// ErrInvalidInvitation error will be returned when a private survey is voted on without a redeemable invitation
+	ErrInvalidInvitation = errors.New("a valid invitation is required to vote in this survey")
+	// ErrSurveyClosed error will be returned when a draft ballot is saved to a survey that is not active
+	ErrSurveyClosed = errors.New("survey is not accepting ballots")
+	// ErrNotTemplateOwner error will be returned when a user deletes an organization template or a template of someone else
+	ErrNotTemplateOwner = errors.New("you can not change this template")
 )
 
+// TransitionError will be returned when a survey status change is not allowed
+type TransitionError struct {
+	From string
+	To   string
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("survey can not go from %s to %s", e.From, e.To)
+}
+
 func Respond(c *gin.Context, code int, err error) {
 	c.JSON(code, gin.H{
 		"error": err.Error(),
 	})
 }
+
+// StatusCode maps known errors to their http status, defaulting to bad request
+func StatusCode(err error) int {
+	var transitionError *TransitionError
+
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrNotOwner), errors.Is(err, ErrPrivacyBudget), errors.Is(err, ErrNoisyResults), errors.Is(err, ErrAnonymousSurvey), errors.Is(err, ErrNotEligible), errors.Is(err, ErrInvalidInvitation), errors.Is(err, ErrNotTemplateOwner):
+		return http.StatusForbidden
+	case errors.Is(err, ErrSurveyLocked), errors.Is(err, ErrStatusChanged), errors.Is(err, ErrSurveyClaimed), errors.Is(err, ErrVotedAlready), errors.Is(err, ErrQuotaFull), errors.Is(err, ErrSurveyClosed), errors.As(err, &transitionError):
+		return http.StatusConflict
+	}
+
+	return http.StatusBadRequest
+}
@@ -0,0 +1,251 @@
+package helpers
+
+import (
+	"archive/zip"
+	"bufio"
+	"dou-survey/app/model"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// TableWriter streams the rows of an export under its columns. Cells are strings,
+// numbers, string lists or nil for empty cells.
+type TableWriter interface {
+	Write(cells []interface{}) error
+	Close() error
+}
+
+// NewTableWriter returns the writer of the format, nothing is written to w until
+// the first row.
+func NewTableWriter(format model.ExportFormat, w io.Writer, columns []string) TableWriter {
+	switch format {
+	case model.ExportFormatXLSX:
+		return &xlsxWriter{w: w, columns: columns}
+	case model.ExportFormatNDJSON:
+		return &ndjsonWriter{w: bufio.NewWriter(w), columns: columns}
+	}
+
+	return &csvWriter{w: csv.NewWriter(w), columns: columns}
+}
+
+// formatCell renders a cell as text, lists are joined with semicolons
+func formatCell(cell interface{}) string {
+	switch value := cell.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case []string:
+		return strings.Join(value, "; ")
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	}
+
+	return fmt.Sprint(cell)
+}
+
+func isNumber(cell interface{}) bool {
+	switch cell.(type) {
+	case int, uint, float64:
+		return true
+	}
+
+	return false
+}
+
+type csvWriter struct {
+	w       *csv.Writer
+	columns []string
+	started bool
+}
+
+func (c *csvWriter) Write(cells []interface{}) error {
+	if err := c.start(); err != nil {
+		return err
+	}
+
+	record := make([]string, len(cells))
+	for i, cell := range cells {
+		record[i] = formatCell(cell)
+		// spreadsheets evaluate text starting like a formula
+		if !isNumber(cell) && record[i] != "" && strings.ContainsRune("=+-@", rune(record[i][0])) {
+			record[i] = "'" + record[i]
+		}
+	}
+
+	return c.w.Write(record)
+}
+
+// start writes the column row
+func (c *csvWriter) start() error {
+	if c.started {
+		return nil
+	}
+	c.started = true
+
+	return c.w.Write(c.columns)
+}
+
+func (c *csvWriter) Close() error {
+	if err := c.start(); err != nil {
+		return err
+	}
+	c.w.Flush()
+
+	return c.w.Error()
+}
+
+type ndjsonWriter struct {
+	w       *bufio.Writer
+	columns []string
+}
+
+// Write encodes the row as an object keeping the order of the columns, write errors
+// stick to the buffer and are returned by a later write or by Close
+func (n *ndjsonWriter) Write(cells []interface{}) error {
+	n.w.WriteByte('{')
+	for i, cell := range cells {
+		if i > 0 {
+			n.w.WriteByte(',')
+		}
+		key, err := json.Marshal(n.columns[i])
+		if err != nil {
+			return err
+		}
+		value, err := json.Marshal(cell)
+		if err != nil {
+			return err
+		}
+		n.w.Write(key)
+		n.w.WriteByte(':')
+		n.w.Write(value)
+	}
+	_, err := n.w.WriteString("}\n")
+
+	return err
+}
+
+func (n *ndjsonWriter) Close() error {
+	return n.w.Flush()
+}
+
+// xlsxWriter writes a workbook with a single sheet of inline strings, so rows never
+// have to be held in memory for a shared string table
+type xlsxWriter struct {
+	w       io.Writer
+	columns []string
+	zip     *zip.Writer
+	sheet   *bufio.Writer
+	rows    int
+}
+
+const xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/></Types>`
+
+const xlsxRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`
+
+const xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Export" sheetId="1" r:id="rId1"/></sheets></workbook>`
+
+const xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`
+
+// start writes the workbook parts and opens the sheet with the column row
+func (x *xlsxWriter) start() error {
+	x.zip = zip.NewWriter(x.w)
+	parts := []struct{ name, content string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRels},
+		{"xl/workbook.xml", xlsxWorkbook},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+	}
+	for _, part := range parts {
+		f, err := x.zip.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err = io.WriteString(f, part.content); err != nil {
+			return err
+		}
+	}
+
+	f, err := x.zip.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return err
+	}
+	x.sheet = bufio.NewWriter(f)
+	x.sheet.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n" + `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+
+	columns := make([]interface{}, len(x.columns))
+	for i, column := range x.columns {
+		columns[i] = column
+	}
+
+	return x.writeRow(columns)
+}
+
+func (x *xlsxWriter) Write(cells []interface{}) error {
+	if x.zip == nil {
+		if err := x.start(); err != nil {
+			return err
+		}
+	}
+
+	return x.writeRow(cells)
+}
+
+func (x *xlsxWriter) writeRow(cells []interface{}) error {
+	x.rows++
+	fmt.Fprintf(x.sheet, `<row r="%d">`, x.rows)
+	for i, cell := range cells {
+		if cell == nil {
+			continue
+		}
+		ref := xlsxColumn(i) + strconv.Itoa(x.rows)
+		if isNumber(cell) {
+			fmt.Fprintf(x.sheet, `<c r="%s"><v>%s</v></c>`, ref, formatCell(cell))
+			continue
+		}
+		fmt.Fprintf(x.sheet, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">`, ref)
+		if err := xml.EscapeText(x.sheet, []byte(formatCell(cell))); err != nil {
+			return err
+		}
+		x.sheet.WriteString(`</t></is></c>`)
+	}
+	_, err := x.sheet.WriteString(`</row>`)
+
+	return err
+}
+
+func (x *xlsxWriter) Close() error {
+	if x.zip == nil {
+		if err := x.start(); err != nil {
+			return err
+		}
+	}
+
+	if _, err := x.sheet.WriteString(`</sheetData></worksheet>`); err != nil {
+		return err
+	}
+	if err := x.sheet.Flush(); err != nil {
+		return err
+	}
+
+	return x.zip.Close()
+}
+
+// xlsxColumn returns the letters of a 0-based column index, A to Z then AA
+func xlsxColumn(index int) string {
+	name := ""
+	for index++; index > 0; index = (index - 1) / 26 {
+		name = string(rune('A'+(index-1)%26)) + name
+	}
+
+	return name
+}
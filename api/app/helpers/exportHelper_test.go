@@ -0,0 +1,87 @@
+package helpers
+
+import (
+	"archive/zip"
+	"bytes"
+	"dou-survey/app/model"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func writeTable(t *testing.T, format model.ExportFormat, rows ...[]interface{}) []byte {
+	var buf bytes.Buffer
+	table := NewTableWriter(format, &buf, []string{"id", "answer", "choices"})
+	for _, row := range rows {
+		if err := table.Write(row); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	if err := table.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestCSVWriter(t *testing.T) {
+	got := string(writeTable(t, model.ExportFormatCSV, []interface{}{1, "=SUM(A1)", []string{"a", "b"}}, []interface{}{2.5, nil, nil}))
+	want := "id,answer,choices\n1,'=SUM(A1),a; b\n2.5,,\n"
+	if got != want {
+		t.Errorf("csv = %q, want %q", got, want)
+	}
+
+	if got := string(writeTable(t, model.ExportFormatCSV)); got != "id,answer,choices\n" {
+		t.Errorf("empty csv = %q, want the column row", got)
+	}
+}
+
+func TestNDJSONWriter(t *testing.T) {
+	got := string(writeTable(t, model.ExportFormatNDJSON, []interface{}{1, "fine", []string{"a"}}, []interface{}{2, nil, nil}))
+	want := "{\"id\":1,\"answer\":\"fine\",\"choices\":[\"a\"]}\n{\"id\":2,\"answer\":null,\"choices\":null}\n"
+	if got != want {
+		t.Errorf("ndjson = %q, want %q", got, want)
+	}
+}
+
+func TestXLSXWriter(t *testing.T) {
+	data := writeTable(t, model.ExportFormatXLSX, []interface{}{1, "<b>", nil})
+
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("xlsx is not a zip archive: %v", err)
+	}
+
+	var sheet string
+	for _, f := range archive.File {
+		if f.Name != "xl/worksheets/sheet1.xml" {
+			continue
+		}
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sheet = string(content)
+	}
+
+	for _, want := range []string{`<c r="C1" t="inlineStr"><is><t xml:space="preserve">choices</t></is></c>`, `<c r="A2"><v>1</v></c>`, `&lt;b&gt;`} {
+		if !strings.Contains(sheet, want) {
+			t.Errorf("sheet misses %s:\n%s", want, sheet)
+		}
+	}
+	if strings.Contains(sheet, `r="C2"`) {
+		t.Errorf("sheet has a cell for an empty value:\n%s", sheet)
+	}
+}
+
+func TestXLSXColumn(t *testing.T) {
+	for index, want := range map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 701: "ZZ", 702: "AAA"} {
+		if got := xlsxColumn(index); got != want {
+			t.Errorf("xlsxColumn(%d) = %s, want %s", index, got, want)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package model
+
+import (
+	"errors"
+	"time"
+)
+
+// Participation records that a user cast a ballot to an anonymous survey. It is the
+// only link between users and anonymous surveys, their votes carry no user. Its table
+// has no rowid, rows are kept in key order and not in the order the ballots came in.
+type Participation struct {
+	UserRefer   uint `gorm:"primaryKey;autoIncrement:false"`
+	SurveyRefer uint `gorm:"primaryKey;autoIncrement:false"`
+}
+
+// DemographicTally counts the anonymous votes of a choice, or with ChoiceRefer 0 the
+// respondents of a question, within a demographic group. Demographics of anonymous
+// voters are only kept in this aggregated form.
+type DemographicTally struct {
+	QuestionRefer uint              `gorm:"primaryKey;autoIncrement:false"`
+	ChoiceRefer   uint              `gorm:"primaryKey;autoIncrement:false"`
+	Dimension     CrosstabDimension `gorm:"primaryKey"`
+	Group         string            `gorm:"column:grp;primaryKey"`
+	Count         int
+}
+
+// ValidateAnonymity checks the settings that conflict with anonymous ballots.
+func (s *Survey) ValidateAnonymity() error {
+	if s.Anonymous && s.BallotChanges {
+		return errors.New("anonymous ballots can not be changed")
+	}
+
+	return nil
+}
+
+// AgeBand returns the AgeBands group of someone born on birthDate at the given time.
+func AgeBand(birthDate, at time.Time) string {
+	age := Age(birthDate, at)
+
+	switch {
+	case age < 18:
+		return AgeBands[0]
+	case age < 25:
+		return AgeBands[1]
+	case age < 35:
+		return AgeBands[2]
+	case age < 45:
+		return AgeBands[3]
+	case age < 55:
+		return AgeBands[4]
+	case age < 65:
+		return AgeBands[5]
+	}
+
+	return AgeBands[6]
+}
+
+// VoterGroups places a voter in one group of every crosstab dimension, the age is
+// taken at ageAt like the crosstab of identified votes does.
+func VoterGroups(user *User, ageAt time.Time) map[CrosstabDimension]string {
+	resident := "false"
+	if user.IsResident != nil && *user.IsResident {
+		resident = "true"
+	}
+
+	return map[CrosstabDimension]string{
+		CrosstabDimensionTotal:          "all",
+		CrosstabDimensionBirthSex:       string(user.BirthSex),
+		CrosstabDimensionGenderIdentity: string(user.GenderIdentity),
+		CrosstabDimensionAgeBand:        AgeBand(user.BirthDate, ageAt),
+		CrosstabDimensionIsResident:     resident,
+	}
+}
+
+// NewDemographicTallies counts the choice votes of one ballot into the groups of its
+// voter. Ranking questions count first preferences only, as the crosstab does.
+func NewDemographicTallies(votes []Vote, groups map[CrosstabDimension]string) []DemographicTally {
+	tallies := make([]DemographicTally, 0)
+	answered := make(map[uint]bool)
+
+	for _, vote := range votes {
+		if vote.ChoiceRefer == nil || vote.Rank > 1 {
+			continue
+		}
+
+		first := !answered[vote.QuestionRefer]
+		answered[vote.QuestionRefer] = true
+		for _, dimension := range CrosstabDimensions {
+			if first {
+				tallies = append(tallies, DemographicTally{vote.QuestionRefer, 0, dimension, groups[dimension], 1})
+			}
+			tallies = append(tallies, DemographicTally{vote.QuestionRefer, *vote.ChoiceRefer, dimension, groups[dimension], 1})
+		}
+	}
+
+	return tallies
+}
@@ -0,0 +1,48 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAgeBand(t *testing.T) {
+	at := time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		birthDate time.Time
+		want      string
+	}{
+		{time.Date(2004, 6, 2, 0, 0, 0, 0, time.UTC), "0-17"},
+		{time.Date(2004, 6, 1, 0, 0, 0, 0, time.UTC), "18-24"},
+		{time.Date(1957, 6, 1, 0, 0, 0, 0, time.UTC), "65+"},
+	}
+	for _, tt := range tests {
+		if got := AgeBand(tt.birthDate, at); got != tt.want {
+			t.Errorf("AgeBand(%v) = %v, want %v", tt.birthDate, got, tt.want)
+		}
+	}
+}
+
+func TestNewDemographicTallies(t *testing.T) {
+	first, second := uint(1), uint(2)
+	votes := []Vote{
+		{QuestionRefer: 1, ChoiceRefer: &first, Rank: 1},
+		{QuestionRefer: 1, ChoiceRefer: &second, Rank: 2},
+		{QuestionRefer: 2},
+	}
+	resident := true
+	groups := VoterGroups(&User{BirthSex: BirthSexWoman, GenderIdentity: GenderIdentityWoman, IsResident: &resident}, time.Now())
+
+	tallies := NewDemographicTallies(votes, groups)
+	// respondents and the first preference in every dimension
+	if len(tallies) != 2*len(CrosstabDimensions) {
+		t.Fatalf("NewDemographicTallies() = %+v, want %d tallies", tallies, 2*len(CrosstabDimensions))
+	}
+	for _, tally := range tallies {
+		if tally.ChoiceRefer == second {
+			t.Errorf("NewDemographicTallies() counted the second preference %+v", tally)
+		}
+		if tally.Dimension == CrosstabDimensionIsResident && tally.Group != "true" {
+			t.Errorf("NewDemographicTallies() IsResident group = %v, want true", tally.Group)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// BallotLogEntry chains a ballot into the append-only log of its survey. The hash of
+// an entry covers the previous hash, so editing any ballot changes every later hash
+// and the root. Voters keep the hash of their ballot as receipt.
+type BallotLogEntry struct {
+	SurveyRefer uint   `gorm:"primaryKey;autoIncrement:false"`
+	Sequence    uint   `gorm:"primaryKey;autoIncrement:false"` // 1 for the first ballot
+	Ballot      string // LoggedBallot as JSON
+	Hash        string `gorm:"uniqueIndex"`
+	Replaces    string `gorm:"index" json:",omitempty"` // copied from the ballot for lookups
+	Receipt     string `gorm:"index" json:",omitempty"` // nonce of an anonymous ballot, its receipt
+}
+
+// PendingBallot holds an anonymous ballot until voting closes. Its table has no rowid
+// and its key is the random receipt, so pending ballots keep no cast order, they are
+// chained into the log in receipt order once the survey closed.
+type PendingBallot struct {
+	Receipt     string `gorm:"primaryKey"`
+	SurveyRefer uint   `gorm:"index"`
+	Ballot      string // LoggedBallot as JSON
+}
+
+// LoggedBallot is the content of a ballot as it is logged, without its voter.
+type LoggedBallot struct {
+	Nonce    string // makes receipts of equal ballots differ
+	Replaces string `json:",omitempty"` // receipt of the ballot this one replaced
+	Votes    []LoggedVote
+}
+
+type LoggedVote struct {
+	QuestionID uint
+	ChoiceID   *uint    `json:",omitempty"`
+	Rank       uint     `json:",omitempty"`
+	Number     *float64 `json:",omitempty"`
+	Text       *string  `json:",omitempty"`
+}
+
+// BallotLog is the exported log of a survey, the root is published once voting closed.
+type BallotLog struct {
+	SurveyID uint
+	Root     string
+	Entries  []BallotLogEntry
+}
+
+// ReceiptCheck tells a voter whether the ballot of a receipt is in the log.
+type ReceiptCheck struct {
+	SurveyID uint
+	Sequence uint // 0 while an anonymous ballot waits for voting to close
+	Included bool
+	Replaced bool   // the voter cast a later ballot
+	Root     string `json:",omitempty"` // published root, empty while voting
+}
+
+// NewLoggedBallot describes the votes of a ballot for the log.
+func NewLoggedBallot(nonce, replaces string, votes []Vote) LoggedBallot {
+	ballot := LoggedBallot{Nonce: nonce, Replaces: replaces, Votes: make([]LoggedVote, 0)}
+	for _, vote := range votes {
+		ballot.Votes = append(ballot.Votes, LoggedVote{vote.QuestionRefer, vote.ChoiceRefer, vote.Rank, vote.Number, vote.Text})
+	}
+
+	return ballot
+}
+
+// BallotHash chains the ballot of a survey to the hash of the previous entry.
+func BallotHash(surveyID uint, previous, ballot string) string {
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%d\n%s\n%s", surveyID, previous, ballot)))
+
+	return hex.EncodeToString(hash[:])
+}
+
+// NewBallotLogEntry appends a ballot to the log after the previous entry, nil for the first.
+func NewBallotLogEntry(surveyID uint, previous *BallotLogEntry, ballot LoggedBallot) (*BallotLogEntry, error) {
+	content, err := json.Marshal(ballot)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &BallotLogEntry{SurveyRefer: surveyID, Sequence: 1, Ballot: string(content), Replaces: ballot.Replaces}
+	previousHash := ""
+	if previous != nil {
+		entry.Sequence = previous.Sequence + 1
+		previousHash = previous.Hash
+	}
+	entry.Hash = BallotHash(surveyID, previousHash, entry.Ballot)
+
+	return entry, nil
+}
+
+// Verify recomputes the hash chain and returns its root, the hash of the last entry.
+func (l *BallotLog) Verify() (root string, err error) {
+	for i, entry := range l.Entries {
+		if entry.SurveyRefer != l.SurveyID || entry.Sequence != uint(i+1) {
+			return "", fmt.Errorf("entry %d is out of sequence", i+1)
+		}
+		if hash := BallotHash(l.SurveyID, root, entry.Ballot); hash != entry.Hash {
+			return "", fmt.Errorf("entry %d does not match its hash", entry.Sequence)
+		}
+		root = entry.Hash
+	}
+
+	return root, nil
+}
+
+// CountChoices counts the votes of every choice in the ballots that were not replaced.
+func (l *BallotLog) CountChoices() (counts map[uint]int, err error) {
+	ballots := make([]LoggedBallot, len(l.Entries))
+	replaced := make(map[string]bool)
+	for i, entry := range l.Entries {
+		if err = json.Unmarshal([]byte(entry.Ballot), &ballots[i]); err != nil {
+			return nil, fmt.Errorf("entry %d: %w", entry.Sequence, err)
+		}
+		if ballots[i].Replaces != "" {
+			replaced[ballots[i].Replaces] = true
+		}
+	}
+
+	counts = make(map[uint]int)
+	for i, entry := range l.Entries {
+		if replaced[entry.Hash] {
+			continue
+		}
+		for _, vote := range ballots[i].Votes {
+			if vote.ChoiceID != nil {
+				counts[*vote.ChoiceID]++
+			}
+		}
+	}
+
+	return counts, nil
+}
@@ -0,0 +1,81 @@
+package model
+
+import (
+	"strings"
+	"testing"
+)
+
+func testBallotLog(t *testing.T) *BallotLog {
+	choiceA, choiceB := uint(1), uint(2)
+	log := &BallotLog{SurveyID: 7}
+
+	ballots := []LoggedBallot{
+		NewLoggedBallot("n1", "", []Vote{{QuestionRefer: 1, ChoiceRefer: &choiceA}}),
+		NewLoggedBallot("n2", "", []Vote{{QuestionRefer: 1, ChoiceRefer: &choiceA}}),
+	}
+	var previous *BallotLogEntry
+	for _, ballot := range ballots {
+		entry, err := NewBallotLogEntry(log.SurveyID, previous, ballot)
+		if err != nil {
+			t.Fatal(err)
+		}
+		log.Entries = append(log.Entries, *entry)
+		previous = entry
+	}
+
+	// the second voter changes to choice B
+	entry, err := NewBallotLogEntry(log.SurveyID, previous, NewLoggedBallot("n3", previous.Hash, []Vote{{QuestionRefer: 1, ChoiceRefer: &choiceB}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	log.Entries = append(log.Entries, *entry)
+
+	return log
+}
+
+func TestBallotLogVerify(t *testing.T) {
+	log := testBallotLog(t)
+
+	root, err := log.Verify()
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if root != log.Entries[2].Hash {
+		t.Errorf("Verify() = %s, want the last hash %s", root, log.Entries[2].Hash)
+	}
+	if log.Entries[2].Sequence != 3 || log.Entries[2].Replaces != log.Entries[1].Hash {
+		t.Errorf("third entry = %+v", log.Entries[2])
+	}
+}
+
+func TestBallotLogVerifyTampered(t *testing.T) {
+	log := testBallotLog(t)
+	log.Entries[0].Ballot = strings.Replace(log.Entries[0].Ballot, `"ChoiceID":1`, `"ChoiceID":2`, 1)
+	if _, err := log.Verify(); err == nil {
+		t.Error("Verify() of an edited ballot succeeded")
+	}
+
+	log = testBallotLog(t)
+	log.Entries = append(log.Entries[:1], log.Entries[2:]...)
+	if _, err := log.Verify(); err == nil {
+		t.Error("Verify() of a log with a removed ballot succeeded")
+	}
+
+	// recomputing the hash of an edited ballot breaks the next entry
+	log = testBallotLog(t)
+	log.Entries[0].Ballot = strings.Replace(log.Entries[0].Ballot, `"ChoiceID":1`, `"ChoiceID":2`, 1)
+	log.Entries[0].Hash = BallotHash(log.SurveyID, "", log.Entries[0].Ballot)
+	if _, err := log.Verify(); err == nil {
+		t.Error("Verify() of a rehashed ballot succeeded")
+	}
+}
+
+func TestBallotLogCountChoices(t *testing.T) {
+	counts, err := testBallotLog(t).CountChoices()
+	if err != nil {
+		t.Fatalf("CountChoices() error = %v", err)
+	}
+	if counts[1] != 1 || counts[2] != 1 || len(counts) != 2 {
+		t.Errorf("CountChoices() = %v, want map[1:1 2:1]", counts)
+	}
+}
@@ -0,0 +1,95 @@
+package model
+
+import (
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Ballot is the current answer set of a user to a survey.
+type Ballot struct {
+	SurveyID   uint
+	Revision   uint // 1 for the first ballot, incremented on every change
+	CastAt     time.Time
+	Changeable bool // the voter may still replace the answers
+	Answers    []Answer
+}
+
+// ReplacedVote keeps a vote of a ballot that its voter replaced.
+type ReplacedVote struct {
+	gorm.Model    `fake:"skip"`
+	UserRefer     uint      `gorm:"index:idx_replaced_vote" fake:"skip"`
+	SurveyRefer   uint      `gorm:"index:idx_replaced_vote" fake:"skip"`
+	Revision      uint      `fake:"skip"` // revision of the replaced ballot
+	QuestionRefer uint      `fake:"skip"`
+	ChoiceRefer   *uint     `fake:"skip"`
+	Rank          uint      `fake:"skip"`
+	Number        *float64  `fake:"skip"`
+	Text          *string   `fake:"skip"`
+	CastAt        time.Time `fake:"skip"`
+}
+
+// NewReplacedVote moves a vote of the given ballot revision into the history.
+func NewReplacedVote(vote Vote, surveyID, revision uint) ReplacedVote {
+	replaced := ReplacedVote{
+		SurveyRefer:   surveyID,
+		Revision:      revision,
+		QuestionRefer: vote.QuestionRefer,
+		ChoiceRefer:   vote.ChoiceRefer,
+		Rank:          vote.Rank,
+		Number:        vote.Number,
+		Text:          vote.Text,
+		CastAt:        vote.CreatedAt,
+	}
+	if vote.UserRefer != nil {
+		replaced.UserRefer = *vote.UserRefer
+	}
+
+	return replaced
+}
+
+// AnswersFrom turns the stored votes of a ballot back into the submitted answers,
+// choices are given by index and ranking choices in preference order.
+func (s *Survey) AnswersFrom(votes []Vote) []Answer {
+	answers := make([]Answer, 0)
+
+	for i := range s.Questions {
+		question := &s.Questions[i]
+
+		answered := false
+		answer := Answer{QuestionID: question.ID}
+		ranks := make(map[uint]uint)
+		for _, vote := range votes {
+			if vote.QuestionRefer != question.ID {
+				continue
+			}
+			answered = true
+
+			if vote.ChoiceRefer == nil {
+				answer.Number = vote.Number
+				answer.Text = vote.Text
+				continue
+			}
+			for index, choice := range question.Choices {
+				if choice.ID == *vote.ChoiceRefer {
+					answer.Choices = append(answer.Choices, uint(index))
+					ranks[uint(index)] = vote.Rank
+				}
+			}
+		}
+		if !answered {
+			continue
+		}
+
+		if question.Type == QuestionTypeRanking {
+			sort.SliceStable(answer.Choices, func(a, b int) bool {
+				return ranks[answer.Choices[a]] < ranks[answer.Choices[b]]
+			})
+		}
+
+		answers = append(answers, answer)
+	}
+
+	return answers
+}
@@ -6,7 +6,10 @@ import (
 
 type Choice struct {
 	gorm.Model    `fake:"skip"`
-	QuestionRefer uint   `binding:"required" fake:"skip"`
-	Value         string `binding:"required" fake:"{randomstring:[Extremely well,Very well,Somewhat well,Not so well]}"`
-	Votes         []Vote `gorm:"foreignKey:ChoiceRefer" fakesize:"100"`
+	QuestionRefer uint     `binding:"required" fake:"skip"`
+	Value         string   `binding:"required" fake:"{randomstring:[Extremely well,Very well,Somewhat well,Not so well]}"`
+	Votes         []Vote   `gorm:"foreignKey:ChoiceRefer" json:",omitempty" fakesize:"100"` // only loaded on request
+	VoteCount     int      `gorm:"-" fake:"skip"`
+	Percentage    float64  `gorm:"-" fake:"skip"`                   // share of the question's respondents, multi-select choices can add up to more than 100
+	MeanRank      *float64 `gorm:"-" json:",omitempty" fake:"skip"` // ranking questions only
 }
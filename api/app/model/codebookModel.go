@@ -0,0 +1,269 @@
+package model
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CodebookValue labels one value code of a variable.
+type CodebookValue struct {
+	Code     int
+	Label    string
+	Value    string `json:",omitempty"` // stored demographic value
+	ChoiceID uint   `json:",omitempty"`
+}
+
+// CodebookVariable describes one column of the coded response export. Names are
+// derived from question and choice IDs, so they stay the same across re-exports.
+type CodebookVariable struct {
+	Name         string
+	Label        string
+	Measure      string            // numeric or string
+	QuestionID   uint              `json:",omitempty"`
+	QuestionType QuestionType      `json:",omitempty"`
+	ChoiceID     uint              `json:",omitempty"` // multi-select and ranking questions have one variable per choice
+	Demographic  CrosstabDimension `json:",omitempty"`
+	Values       []CodebookValue   `json:",omitempty"`
+}
+
+const (
+	MeasureNumeric = "numeric"
+	MeasureString  = "string"
+)
+
+// Codebook maps the variables of the coded response export to the questions and
+// choices of a survey. Empty cells are missing: unanswered questions and demographics
+// of respondents in groups smaller than MinCellSize.
+type Codebook struct {
+	SurveyID    uint
+	Subject     string
+	MinCellSize int `json:",omitempty"`
+	Variables   []CodebookVariable
+
+	ageAt time.Time
+}
+
+var birthSexCodes = []CodebookValue{
+	{Code: 1, Label: "Woman", Value: string(BirthSexWoman)},
+	{Code: 2, Label: "Man", Value: string(BirthSexMan)},
+	{Code: 9, Label: "Prefer not to respond", Value: string(BirthSexNoRespond)},
+}
+
+var genderIdentityCodes = []CodebookValue{
+	{Code: 1, Label: "Woman", Value: string(GenderIdentityWoman)},
+	{Code: 2, Label: "Man", Value: string(GenderIdentityMan)},
+	{Code: 3, Label: "Transgender", Value: string(GenderIdentityTransgender)},
+	{Code: 4, Label: "Non-binary/non-conforming", Value: string(GenderIdentityNonBin)},
+	{Code: 9, Label: "Prefer not to respond", Value: string(GenderIdentityNoRespond)},
+}
+
+var isResidentCodes = []CodebookValue{
+	{Code: 0, Label: "No", Value: "false"},
+	{Code: 1, Label: "Yes", Value: "true"},
+}
+
+var selectedCodes = []CodebookValue{
+	{Code: 0, Label: "Not selected"},
+	{Code: 1, Label: "Selected"},
+}
+
+// NewCodebook builds the codebook of a survey from its questions and choices, the
+// choices of single, likert and multi-select questions are coded by their position.
+// Anonymous surveys have no demographic variables.
+func NewCodebook(survey *Survey, minCellSize int) *Codebook {
+	codebook := &Codebook{SurveyID: survey.ID, Subject: survey.Subject, ageAt: survey.DateEnd}
+	codebook.Variables = []CodebookVariable{{Name: "respondent", Label: "Respondent number", Measure: MeasureNumeric}}
+
+	if !survey.Anonymous {
+		codebook.MinCellSize = minCellSize
+		ageBands := make([]CodebookValue, len(AgeBands))
+		for i, band := range AgeBands {
+			ageBands[i] = CodebookValue{Code: i + 1, Label: band, Value: band}
+		}
+		codebook.Variables = append(codebook.Variables,
+			CodebookVariable{Name: "birth_sex", Label: "Birth sex", Measure: MeasureNumeric, Demographic: CrosstabDimensionBirthSex, Values: birthSexCodes},
+			CodebookVariable{Name: "gender_identity", Label: "Gender identity", Measure: MeasureNumeric, Demographic: CrosstabDimensionGenderIdentity, Values: genderIdentityCodes},
+			CodebookVariable{Name: "age_band", Label: "Age band at the end of the survey", Measure: MeasureNumeric, Demographic: CrosstabDimensionAgeBand, Values: ageBands},
+			CodebookVariable{Name: "is_resident", Label: "Resident", Measure: MeasureNumeric, Demographic: CrosstabDimensionIsResident, Values: isResidentCodes},
+		)
+	}
+
+	for _, question := range survey.Questions {
+		name := "q" + strconv.FormatUint(uint64(question.ID), 10)
+		variable := CodebookVariable{Name: name, Label: question.Value, Measure: MeasureNumeric, QuestionID: question.ID, QuestionType: question.Type}
+
+		switch question.Type {
+		case QuestionTypeText:
+			variable.Measure = MeasureString
+		case QuestionTypeNumeric:
+		case QuestionTypeMulti, QuestionTypeRanking:
+			for _, choice := range question.Choices {
+				perChoice := variable
+				perChoice.Name = fmt.Sprintf("%s_c%d", name, choice.ID)
+				perChoice.Label = question.Value + ": " + choice.Value
+				perChoice.ChoiceID = choice.ID
+				if question.Type == QuestionTypeMulti {
+					perChoice.Values = selectedCodes
+				}
+				codebook.Variables = append(codebook.Variables, perChoice)
+			}
+			continue
+		default:
+			variable.Values = make([]CodebookValue, len(question.Choices))
+			for i, choice := range question.Choices {
+				variable.Values[i] = CodebookValue{Code: i + 1, Label: choice.Value, ChoiceID: choice.ID}
+			}
+		}
+
+		codebook.Variables = append(codebook.Variables, variable)
+	}
+
+	return codebook
+}
+
+// Columns names the columns of the coded export.
+func (c *Codebook) Columns() []string {
+	columns := make([]string, len(c.Variables))
+	for i, variable := range c.Variables {
+		columns[i] = variable.Name
+	}
+
+	return columns
+}
+
+// cells codes the answers of a respondent, user is nil when the demographics of
+// the respondent are not disclosed
+func (c *Codebook) cells(number int, user *User, answers map[uint][]ResponseRow) []interface{} {
+	var groups map[CrosstabDimension]string
+	if user != nil {
+		groups = VoterGroups(user, c.ageAt)
+	}
+
+	cells := make([]interface{}, len(c.Variables))
+	for i := range c.Variables {
+		variable := &c.Variables[i]
+		switch {
+		case variable.Name == "respondent":
+			cells[i] = number
+		case variable.Demographic != "":
+			if groups != nil {
+				cells[i] = variable.code(groups[variable.Demographic])
+			}
+		default:
+			cells[i] = variable.answer(answers[variable.QuestionID])
+		}
+	}
+
+	return cells
+}
+
+// code returns the code of a demographic value, nil if it has none
+func (v *CodebookVariable) code(value string) interface{} {
+	for _, code := range v.Values {
+		if code.Value == value {
+			return code.Code
+		}
+	}
+
+	return nil
+}
+
+// answer codes the votes of a respondent for the variable, nil for unanswered questions
+func (v *CodebookVariable) answer(votes []ResponseRow) interface{} {
+	if len(votes) == 0 {
+		return nil
+	}
+
+	switch v.QuestionType {
+	case QuestionTypeNumeric:
+		return optionalFloat(votes[0].Number)
+	case QuestionTypeText:
+		if votes[0].Text == nil {
+			return nil
+		}
+		return *votes[0].Text
+	case QuestionTypeMulti:
+		for _, vote := range votes {
+			if vote.ChoiceID != nil && *vote.ChoiceID == v.ChoiceID {
+				return 1
+			}
+		}
+		return 0
+	case QuestionTypeRanking:
+		for _, vote := range votes {
+			if vote.ChoiceID != nil && *vote.ChoiceID == v.ChoiceID {
+				return int(vote.Rank)
+			}
+		}
+		return nil
+	}
+
+	for _, code := range v.Values {
+		if votes[0].ChoiceID != nil && *votes[0].ChoiceID == code.ChoiceID {
+			return code.Code
+		}
+	}
+
+	return nil
+}
+
+// SPSSSyntax returns the SPSS syntax reading the coded csv export from dataFile and
+// applying the variable and value labels of the codebook.
+func (c *Codebook) SPSSSyntax(dataFile string) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "* Codebook of survey %d: %s.\n", c.SurveyID, spssComment(c.Subject))
+	fmt.Fprintf(&b, "GET DATA /TYPE=TXT /FILE=%s /ENCODING='UTF8' /DELIMITERS=\",\" /QUALIFIER='\"' /ARRANGEMENT=DELIMITED /FIRSTCASE=2\n  /VARIABLES=", spssString(dataFile))
+	for _, variable := range c.Variables {
+		format := "F8.0"
+		switch {
+		case variable.Measure == MeasureString:
+			format = "A1024"
+		case variable.QuestionType == QuestionTypeNumeric:
+			format = "F16.4"
+		}
+		fmt.Fprintf(&b, "\n    %s %s", variable.Name, format)
+	}
+	b.WriteString(".\n\nVARIABLE LABELS")
+	for _, variable := range c.Variables {
+		fmt.Fprintf(&b, "\n  %s %s", variable.Name, spssString(variable.Label))
+	}
+	b.WriteString(".\n")
+
+	labelled := make([]CodebookVariable, 0)
+	for _, variable := range c.Variables {
+		if len(variable.Values) > 0 {
+			labelled = append(labelled, variable)
+		}
+	}
+	if len(labelled) > 0 {
+		b.WriteString("\nVALUE LABELS")
+		for i, variable := range labelled {
+			if i > 0 {
+				b.WriteString("\n  /")
+			}
+			fmt.Fprintf(&b, "\n  %s", variable.Name)
+			for _, value := range variable.Values {
+				fmt.Fprintf(&b, "\n    %d %s", value.Code, spssString(value.Label))
+			}
+		}
+		b.WriteString(".\n")
+	}
+	b.WriteString("\nEXECUTE.\n")
+
+	return b.String()
+}
+
+// spssString quotes a string literal of SPSS syntax
+func spssString(value string) string {
+	value = strings.NewReplacer("\r", " ", "\n", " ").Replace(value)
+
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+// spssComment keeps a comment on one line without ending it early
+func spssComment(value string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ", ".", "").Replace(value)
+}
@@ -0,0 +1,78 @@
+package model
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewCodebook(t *testing.T) {
+	survey := exportSurvey()
+	survey.Questions = append(survey.Questions, Question{Type: QuestionTypeMulti, Choices: []Choice{{Value: "m"}}})
+	survey.Questions[3].ID, survey.Questions[3].Choices[0].ID = 4, 40
+
+	codebook := NewCodebook(survey, 5)
+	want := []string{"respondent", "birth_sex", "gender_identity", "age_band", "is_resident", "q1", "q2_c20", "q2_c21", "q3", "q4_c40"}
+	if got := codebook.Columns(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Columns() = %v, want %v", got, want)
+	}
+
+	// names follow the ids, not the position of the questions
+	survey.Questions[0], survey.Questions[2] = survey.Questions[2], survey.Questions[0]
+	if got := NewCodebook(survey, 5).Variables[5].Name; got != "q3" {
+		t.Errorf("first question variable = %s, want q3", got)
+	}
+
+	if values := codebook.Variables[5].Values; len(values) != 2 || values[1].Code != 2 || values[1].ChoiceID != 11 {
+		t.Errorf("q1 values = %+v, want the choices coded by position", values)
+	}
+	if codebook.Variables[8].Measure != MeasureString {
+		t.Errorf("text variable measure = %s", codebook.Variables[8].Measure)
+	}
+
+	survey.Anonymous = true
+	if got := NewCodebook(survey, 5); got.MinCellSize != 0 || got.Variables[1].Demographic != "" {
+		t.Errorf("anonymous codebook has demographics: %+v", got.Variables[1])
+	}
+}
+
+func TestCodedResponseMatrix(t *testing.T) {
+	survey := exportSurvey()
+	resident := true
+	user := &User{BirthSex: BirthSexMan, GenderIdentity: GenderIdentityNonBin, BirthDate: time.Date(1960, 1, 1, 0, 0, 0, 0, time.UTC), IsResident: &resident}
+
+	matrix := NewCodedResponseMatrix(survey, 1)
+	matrix.CountRespondent(user)
+
+	no, a := uint(11), uint(20)
+	matrix.Add(ResponseRow{Respondent: "k1", QuestionID: 1, ChoiceID: &no, User: user})
+	matrix.Add(ResponseRow{Respondent: "k1", QuestionID: 2, ChoiceID: &a, Rank: 2, User: user})
+
+	want := []interface{}{1, 2, 4, 6, 1, 2, 2, nil, nil}
+	if got := matrix.Flush(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Flush() = %v, want %v", got, want)
+	}
+}
+
+func TestSPSSSyntax(t *testing.T) {
+	survey := exportSurvey()
+	survey.ID = 7
+	survey.Subject = "Town. hall"
+	survey.Questions[0].Value = "Isn't it\nfine?"
+
+	syntax := NewCodebook(survey, 5).SPSSSyntax("survey-7-coded.csv")
+	for _, want := range []string{
+		"* Codebook of survey 7: Town hall.",
+		"/FILE='survey-7-coded.csv'",
+		"q1 F8.0",
+		"q3 A1024",
+		"q1 'Isn''t it fine?'",
+		"/\n  q1\n    1 'yes'\n    2 'no'",
+		"EXECUTE.",
+	} {
+		if !strings.Contains(syntax, want) {
+			t.Errorf("syntax misses %q:\n%s", want, syntax)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package model
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// Condition shows its question only when the source question is shown and
+// its answer includes the given choice. All conditions of a question must hold.
+// Questions and choices are referenced by their position, like vote indices.
+type Condition struct {
+	gorm.Model    `fake:"skip"`
+	QuestionRefer uint `fake:"skip"`
+	SourceIndex   uint // position of the source question in the survey
+	ChoiceIndex   uint // position of the choice in the source question
+}
+
+// ValidateConditions rejects display conditions that point to missing
+// questions or choices, that form a cycle or that can never be satisfied.
+func (s *Survey) ValidateConditions() error {
+	for i, question := range s.Questions {
+		for _, condition := range question.Conditions {
+			if int(condition.SourceIndex) >= len(s.Questions) {
+				return errors.New("condition refers to a missing question")
+			}
+			if int(condition.SourceIndex) == i {
+				return errors.New("question can not depend on itself")
+			}
+
+			source := s.Questions[condition.SourceIndex]
+			if !source.HasChoices() {
+				return errors.New("conditions can only depend on choice questions")
+			}
+			if int(condition.ChoiceIndex) >= len(source.Choices) {
+				return errors.New("condition refers to a missing choice")
+			}
+		}
+	}
+
+	order, err := s.conditionOrder()
+	if err != nil {
+		return err
+	}
+
+	for _, i := range order {
+		if !s.Questions[i].satisfiable(s.Questions) {
+			return errors.New("question can never be shown with its conditions")
+		}
+	}
+
+	return nil
+}
+
+// conditionOrder sorts question positions so that every question comes
+// after the questions its conditions depend on.
+func (s *Survey) conditionOrder() (order []int, err error) {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	state := make([]int, len(s.Questions))
+	order = make([]int, 0, len(s.Questions))
+
+	var visit func(i int) error
+	visit = func(i int) error {
+		switch state[i] {
+		case visiting:
+			return errors.New("question conditions are circular")
+		case visited:
+			return nil
+		}
+
+		state[i] = visiting
+		for _, condition := range s.Questions[i].Conditions {
+			if err := visit(int(condition.SourceIndex)); err != nil {
+				return err
+			}
+		}
+		state[i] = visited
+		order = append(order, i)
+
+		return nil
+	}
+
+	for i := range s.Questions {
+		if err := visit(i); err != nil {
+			return nil, err
+		}
+	}
+
+	return order, nil
+}
+
+// satisfiable reports whether some answers show the question. Conditions only
+// combine with and, so showing the question requires every question it depends
+// on, directly or through their own conditions, to be shown with all the choices
+// asked of it selected. Sources reached through several paths must allow the
+// combined choices.
+func (q *Question) satisfiable(questions []Question) bool {
+	required := make(map[uint]map[uint]bool)
+	pending := []*Question{q}
+	for len(pending) > 0 {
+		question := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+
+		for _, condition := range question.Conditions {
+			if required[condition.SourceIndex] == nil {
+				required[condition.SourceIndex] = make(map[uint]bool)
+				pending = append(pending, &questions[condition.SourceIndex])
+			}
+			required[condition.SourceIndex][condition.ChoiceIndex] = true
+		}
+	}
+
+	for sourceIndex, choices := range required {
+		source := questions[sourceIndex]
+		switch source.Type {
+		case QuestionTypeSingle, QuestionTypeLikert:
+			if len(choices) > 1 {
+				return false
+			}
+		case QuestionTypeMulti:
+			if source.MaxChoices != 0 && len(choices) > int(source.MaxChoices) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// ValidateAnswers checks a ballot against the survey: every shown question
+// must be answered exactly once and hidden questions must not be answered.
+func (s *Survey) ValidateAnswers(answers []Answer) error {
+	answered := make(map[uint]Answer)
+	for _, answer := range answers {
+		question := s.Question(answer.QuestionID)
+		if question == nil {
+			return errors.New("question does not belong to this survey")
+		}
+		if _, ok := answered[answer.QuestionID]; ok {
+			return errors.New("question answered more than once")
+		}
+		answered[answer.QuestionID] = answer
+	}
+
+	order, err := s.conditionOrder()
+	if err != nil {
+		return err
+	}
+
+	shown := make([]bool, len(s.Questions))
+	for _, i := range order {
+		question := &s.Questions[i]
+		shown[i] = question.shown(s.Questions, shown, answered)
+
+		answer, ok := answered[question.ID]
+		if !shown[i] {
+			if ok {
+				return errors.New("answer given to a hidden question")
+			}
+			continue
+		}
+		if !ok {
+			return errors.New("vote count does not match")
+		}
+		if err := question.ValidateAnswer(answer); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// shown evaluates the display conditions of the question against the answers.
+func (q *Question) shown(questions []Question, shown []bool, answered map[uint]Answer) bool {
+	for _, condition := range q.Conditions {
+		if !shown[condition.SourceIndex] {
+			return false
+		}
+
+		answer, ok := answered[questions[condition.SourceIndex].ID]
+		if !ok {
+			return false
+		}
+
+		selected := false
+		for _, index := range answer.Choices {
+			if index == condition.ChoiceIndex {
+				selected = true
+			}
+		}
+		if !selected {
+			return false
+		}
+	}
+
+	return true
+}
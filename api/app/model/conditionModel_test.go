@@ -0,0 +1,113 @@
+package model
+
+import (
+	"testing"
+)
+
+func conditionSurvey(conditions ...[]Condition) Survey {
+	survey := Survey{}
+	for i, c := range conditions {
+		question := Question{Value: "q", Type: QuestionTypeSingle, Choices: []Choice{{Value: "yes"}, {Value: "no"}}, Conditions: c}
+		question.ID = uint(i + 1)
+		survey.Questions = append(survey.Questions, question)
+	}
+
+	return survey
+}
+
+func TestSurveyValidateConditions(t *testing.T) {
+	tests := []struct {
+		name    string
+		survey  Survey
+		wantErr bool
+	}{
+		{
+			name:   "no conditions",
+			survey: conditionSurvey(nil, nil),
+		},
+		{
+			name:   "depends on earlier question",
+			survey: conditionSurvey(nil, []Condition{{SourceIndex: 0, ChoiceIndex: 0}}),
+		},
+		{
+			name:    "circular",
+			survey:  conditionSurvey([]Condition{{SourceIndex: 1}}, []Condition{{SourceIndex: 0}}),
+			wantErr: true,
+		},
+		{
+			name:    "missing choice",
+			survey:  conditionSurvey(nil, []Condition{{SourceIndex: 0, ChoiceIndex: 2}}),
+			wantErr: true,
+		},
+		{
+			name:    "two choices of a single choice question",
+			survey:  conditionSurvey(nil, []Condition{{SourceIndex: 0, ChoiceIndex: 0}, {SourceIndex: 0, ChoiceIndex: 1}}),
+			wantErr: true,
+		},
+		{
+			name: "depends on unreachable question",
+			survey: conditionSurvey(nil,
+				[]Condition{{SourceIndex: 0, ChoiceIndex: 0}, {SourceIndex: 0, ChoiceIndex: 1}},
+				[]Condition{{SourceIndex: 1, ChoiceIndex: 0}}),
+			wantErr: true,
+		},
+		{
+			name: "sources shown by different answers",
+			survey: conditionSurvey(nil,
+				[]Condition{{SourceIndex: 0, ChoiceIndex: 0}},
+				[]Condition{{SourceIndex: 0, ChoiceIndex: 1}},
+				[]Condition{{SourceIndex: 1, ChoiceIndex: 0}, {SourceIndex: 2, ChoiceIndex: 0}}),
+			wantErr: true,
+		},
+		{
+			name: "sources shown by the same answer",
+			survey: conditionSurvey(nil,
+				[]Condition{{SourceIndex: 0, ChoiceIndex: 0}},
+				[]Condition{{SourceIndex: 0, ChoiceIndex: 0}},
+				[]Condition{{SourceIndex: 1, ChoiceIndex: 0}, {SourceIndex: 2, ChoiceIndex: 1}}),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.survey.ValidateConditions(); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateConditions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSurveyValidateAnswers(t *testing.T) {
+	survey := conditionSurvey(nil, []Condition{{SourceIndex: 0, ChoiceIndex: 0}})
+
+	tests := []struct {
+		name    string
+		answers []Answer
+		wantErr bool
+	}{
+		{
+			name:    "shown question answered",
+			answers: []Answer{{QuestionID: 1, Choices: []uint{0}}, {QuestionID: 2, Choices: []uint{1}}},
+		},
+		{
+			name:    "shown question missing",
+			answers: []Answer{{QuestionID: 1, Choices: []uint{0}}},
+			wantErr: true,
+		},
+		{
+			name:    "hidden question skipped",
+			answers: []Answer{{QuestionID: 1, Choices: []uint{1}}},
+		},
+		{
+			name:    "hidden question answered",
+			answers: []Answer{{QuestionID: 1, Choices: []uint{1}}, {QuestionID: 2, Choices: []uint{1}}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := survey.ValidateAnswers(tt.answers); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateAnswers() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package model
+
+// CrosstabDimension is a demographic attribute of voters that results are broken down by.
+type CrosstabDimension string
+
+const (
+	CrosstabDimensionTotal          CrosstabDimension = "Total" // every voter in a single group
+	CrosstabDimensionBirthSex       CrosstabDimension = "BirthSex"
+	CrosstabDimensionGenderIdentity CrosstabDimension = "GenderIdentity"
+	CrosstabDimensionAgeBand        CrosstabDimension = "AgeBand" // age at the end of the survey
+	CrosstabDimensionIsResident     CrosstabDimension = "IsResident"
+)
+
+// CrosstabDimensions lists the dimensions in the order they are reported.
+var CrosstabDimensions = []CrosstabDimension{
+	CrosstabDimensionTotal,
+	CrosstabDimensionBirthSex,
+	CrosstabDimensionGenderIdentity,
+	CrosstabDimensionAgeBand,
+	CrosstabDimensionIsResident,
+}
+
+// CrosstabRow is one aggregated cell as computed by the store: the votes of a
+// choice within a demographic group and the respondents of the question in that group.
+type CrosstabRow struct {
+	QuestionID  uint
+	ChoiceID    uint
+	Dimension   CrosstabDimension
+	Group       string
+	Count       int
+	Respondents int
+	Percentage  float64
+}
+
+// Crosstab breaks the choice counts of every choice question down by demographics.
+// Ranking questions count first preferences, numeric and text questions are left out.
+type Crosstab struct {
+	SurveyID    uint
+	MinCellSize int      // smaller demographic groups are merged
+	Privacy     *Privacy `json:",omitempty"`
+	Questions   []CrosstabQuestion
+}
+
+type CrosstabQuestion struct {
+	QuestionID uint
+	Value      string
+	Type       QuestionType
+	Dimensions []CrosstabTable
+}
+
+// CrosstabTable holds one column per group of a dimension.
+type CrosstabTable struct {
+	Dimension  CrosstabDimension
+	Suppressed bool // too few respondents to disclose any group
+	Groups     []CrosstabGroup
+}
+
+type CrosstabGroup struct {
+	Group       string
+	Respondents int // voters of the group that answered the question
+	Choices     []CrosstabCell
+}
+
+type CrosstabCell struct {
+	ChoiceID   uint
+	Value      string
+	Count      int
+	Percentage float64 // share of the group's respondents, multi-select groups can add up to more than 100
+	Suppressed bool    `json:",omitempty"` // too few votes to disclose, the count is left out
+}
+
+// NewCrosstab arranges the aggregated rows along the questions and choices of
+// the survey, choices nobody in a group selected are reported with a zero count.
+func NewCrosstab(survey *Survey, rows []CrosstabRow) *Crosstab {
+	type groupKey struct {
+		questionID uint
+		dimension  CrosstabDimension
+	}
+
+	groups := make(map[groupKey][]string)
+	respondents := make(map[groupKey]map[string]int)
+	cells := make(map[groupKey]map[string]map[uint]CrosstabRow)
+	for _, row := range rows {
+		key := groupKey{row.QuestionID, row.Dimension}
+		if respondents[key] == nil {
+			respondents[key] = make(map[string]int)
+			cells[key] = make(map[string]map[uint]CrosstabRow)
+		}
+		if _, ok := respondents[key][row.Group]; !ok {
+			// rows come ordered by group
+			groups[key] = append(groups[key], row.Group)
+			cells[key][row.Group] = make(map[uint]CrosstabRow)
+		}
+		respondents[key][row.Group] = row.Respondents
+		cells[key][row.Group][row.ChoiceID] = row
+	}
+
+	crosstab := &Crosstab{SurveyID: survey.ID, Questions: make([]CrosstabQuestion, 0)}
+	for _, question := range survey.Questions {
+		if !question.HasChoices() {
+			continue
+		}
+
+		crosstabQuestion := CrosstabQuestion{QuestionID: question.ID, Value: question.Value, Type: question.Type, Dimensions: make([]CrosstabTable, 0)}
+		for _, dimension := range CrosstabDimensions {
+			key := groupKey{question.ID, dimension}
+			table := CrosstabTable{Dimension: dimension, Groups: make([]CrosstabGroup, 0)}
+
+			for _, group := range groups[key] {
+				crosstabGroup := CrosstabGroup{Group: group, Respondents: respondents[key][group], Choices: make([]CrosstabCell, 0)}
+				for _, choice := range question.Choices {
+					row := cells[key][group][choice.ID]
+					crosstabGroup.Choices = append(crosstabGroup.Choices, CrosstabCell{ChoiceID: choice.ID, Value: choice.Value, Count: row.Count, Percentage: row.Percentage})
+				}
+				table.Groups = append(table.Groups, crosstabGroup)
+			}
+
+			crosstabQuestion.Dimensions = append(crosstabQuestion.Dimensions, table)
+		}
+
+		crosstab.Questions = append(crosstab.Questions, crosstabQuestion)
+	}
+
+	return crosstab
+}
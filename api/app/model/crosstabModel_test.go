@@ -0,0 +1,76 @@
+package model
+
+import (
+	"testing"
+)
+
+func TestNewCrosstab(t *testing.T) {
+	survey := conditionSurvey(nil)
+	survey.Questions[0].Choices[0].ID = 10
+	survey.Questions[0].Choices[1].ID = 11
+	survey.Questions = append(survey.Questions, Question{Value: "text", Type: QuestionTypeText})
+
+	rows := []CrosstabRow{
+		{QuestionID: 1, ChoiceID: 10, Dimension: CrosstabDimensionBirthSex, Group: "man", Count: 1, Respondents: 1, Percentage: 100},
+		{QuestionID: 1, ChoiceID: 11, Dimension: CrosstabDimensionBirthSex, Group: "woman", Count: 2, Respondents: 2, Percentage: 100},
+	}
+
+	crosstab := NewCrosstab(&survey, rows)
+	if len(crosstab.Questions) != 1 {
+		t.Fatalf("NewCrosstab() questions = %v, want 1", len(crosstab.Questions))
+	}
+
+	table := crosstab.Questions[0].Dimensions[1]
+	if table.Dimension != CrosstabDimensionBirthSex || len(table.Groups) != 2 {
+		t.Fatalf("NewCrosstab() table = %+v", table)
+	}
+
+	man := table.Groups[0]
+	if man.Group != "man" || len(man.Choices) != 2 || man.Choices[0].Count != 1 || man.Choices[1].Count != 0 {
+		t.Errorf("NewCrosstab() group = %+v", man)
+	}
+}
+
+func TestCrosstabSuppressCells(t *testing.T) {
+	survey := &Survey{Questions: []Question{{Value: "q", Type: QuestionTypeSingle, Choices: []Choice{{Value: "a"}, {Value: "b"}, {Value: "c"}}}}}
+	survey.Questions[0].ID = 1
+	for i := range survey.Questions[0].Choices {
+		survey.Questions[0].Choices[i].ID = uint(10 + i)
+	}
+
+	rows := make([]CrosstabRow, 0)
+	for _, group := range []struct {
+		name   string
+		counts []int
+	}{{"man", []int{19, 1, 0}}, {"woman", []int{5, 5, 0}}} {
+		respondents := group.counts[0] + group.counts[1] + group.counts[2]
+		for i, count := range group.counts {
+			rows = append(rows, CrosstabRow{QuestionID: 1, ChoiceID: uint(10 + i), Dimension: CrosstabDimensionBirthSex, Group: group.name,
+				Count: count, Respondents: respondents, Percentage: float64(count*100) / float64(respondents)})
+		}
+	}
+
+	crosstab := NewCrosstab(survey, rows)
+	crosstab.Suppress(5)
+
+	groups := crosstab.Questions[0].Dimensions[1].Groups
+	if len(groups) != 2 || groups[0].Respondents != 20 {
+		t.Fatalf("groups = %+v, want both groups disclosed", groups)
+	}
+
+	// the single vote, and the cells it could be derived from
+	want := [][]bool{{false, true, true}, {false, true, true}}
+	for g, group := range groups {
+		for c, cell := range group.Choices {
+			if cell.Suppressed != want[g][c] {
+				t.Errorf("%s %s suppressed = %v, want %v", group.Group, cell.Value, cell.Suppressed, want[g][c])
+			}
+			if cell.Suppressed && (cell.Count != 0 || cell.Percentage != 0) {
+				t.Errorf("%s %s discloses %+v", group.Group, cell.Value, cell)
+			}
+		}
+	}
+	if groups[0].Choices[0].Count != 19 || groups[1].Choices[0].Count != 5 {
+		t.Errorf("large cells = %+v, %+v", groups[0].Choices[0], groups[1].Choices[0])
+	}
+}
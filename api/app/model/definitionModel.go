@@ -0,0 +1,301 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// DefinitionVersion is the version of the survey definition documents written by
+// this release. Documents of a later version are rejected.
+const DefinitionVersion = 1
+
+// DefinitionFormat is the encoding of a survey definition document.
+type DefinitionFormat string
+
+const (
+	DefinitionFormatJSON DefinitionFormat = "json"
+	DefinitionFormatYAML DefinitionFormat = "yaml"
+)
+
+// SurveyDefinition is the portable document of a survey: its texts, dates, settings
+// and questions without any ids, votes or review state, so it can be kept in git and
+// imported in another environment. Invitees are left out as they are personal data.
+type SurveyDefinition struct {
+	Version       int                    `json:"version" yaml:"version"`
+	Subject       string                 `json:"subject" yaml:"subject"`
+	Description   string                 `json:"description" yaml:"description"`
+	DateStart     time.Time              `json:"date_start" yaml:"date_start"`
+	DateEnd       time.Time              `json:"date_end" yaml:"date_end"`
+	Sensitive     bool                   `json:"sensitive,omitempty" yaml:"sensitive,omitempty"`
+	Audience      uint                   `json:"audience,omitempty" yaml:"audience,omitempty"`
+	MinCellSize   uint                   `json:"min_cell_size,omitempty" yaml:"min_cell_size,omitempty"`
+	Noise         NoiseMechanism         `json:"noise,omitempty" yaml:"noise,omitempty"`
+	PrivacyBudget float64                `json:"privacy_budget,omitempty" yaml:"privacy_budget,omitempty"`
+	Epsilon       float64                `json:"epsilon,omitempty" yaml:"epsilon,omitempty"`
+	BallotChanges bool                   `json:"ballot_changes,omitempty" yaml:"ballot_changes,omitempty"`
+	Anonymous     bool                   `json:"anonymous,omitempty" yaml:"anonymous,omitempty"`
+	Private       bool                   `json:"private,omitempty" yaml:"private,omitempty"`
+	Eligibility   *EligibilityDefinition `json:"eligibility,omitempty" yaml:"eligibility,omitempty"`
+	RespondentCap uint                   `json:"respondent_cap,omitempty" yaml:"respondent_cap,omitempty"`
+	Quotas        []QuotaDefinition      `json:"quotas,omitempty" yaml:"quotas,omitempty"`
+	Questions     []QuestionDefinition   `json:"questions" yaml:"questions"`
+}
+
+type EligibilityDefinition struct {
+	ResidentsOnly    bool             `json:"residents_only,omitempty" yaml:"residents_only,omitempty"`
+	MinAge           *uint            `json:"min_age,omitempty" yaml:"min_age,omitempty"`
+	MaxAge           *uint            `json:"max_age,omitempty" yaml:"max_age,omitempty"`
+	BirthSexes       []BirthSex       `json:"birth_sexes,omitempty" yaml:"birth_sexes,omitempty"`
+	GenderIdentities []GenderIdentity `json:"gender_identities,omitempty" yaml:"gender_identities,omitempty"`
+}
+
+type QuotaDefinition struct {
+	BirthSex       BirthSex       `json:"birth_sex,omitempty" yaml:"birth_sex,omitempty"`
+	GenderIdentity GenderIdentity `json:"gender_identity,omitempty" yaml:"gender_identity,omitempty"`
+	MinAge         *uint          `json:"min_age,omitempty" yaml:"min_age,omitempty"`
+	MaxAge         *uint          `json:"max_age,omitempty" yaml:"max_age,omitempty"`
+	IsResident     *bool          `json:"is_resident,omitempty" yaml:"is_resident,omitempty"`
+	Target         uint           `json:"target" yaml:"target"`
+}
+
+// QuestionDefinition is a question with its choices in order. The key names the
+// question in the conditions of later questions, it defaults to q and the 1-based
+// position of the question.
+type QuestionDefinition struct {
+	Key        string                `json:"key,omitempty" yaml:"key,omitempty"`
+	Text       string                `json:"text" yaml:"text"`
+	Type       QuestionType          `json:"type,omitempty" yaml:"type,omitempty"`
+	MinChoices uint                  `json:"min_choices,omitempty" yaml:"min_choices,omitempty"`
+	MaxChoices uint                  `json:"max_choices,omitempty" yaml:"max_choices,omitempty"`
+	MinNumber  *float64              `json:"min_number,omitempty" yaml:"min_number,omitempty"`
+	MaxNumber  *float64              `json:"max_number,omitempty" yaml:"max_number,omitempty"`
+	Choices    []string              `json:"choices,omitempty" yaml:"choices,omitempty"`
+	ShowIf     []ConditionDefinition `json:"show_if,omitempty" yaml:"show_if,omitempty"`
+}
+
+// ConditionDefinition shows a question when the question with the given key was
+// answered with the choice of the given text.
+type ConditionDefinition struct {
+	Question string `json:"question" yaml:"question"`
+	Choice   string `json:"choice" yaml:"choice"`
+}
+
+// ParseSurveyDefinition decodes a definition document, unknown fields are rejected
+// so that misspelled settings are not silently dropped.
+func ParseSurveyDefinition(data []byte, format DefinitionFormat) (*SurveyDefinition, error) {
+	definition := &SurveyDefinition{}
+
+	switch format {
+	case DefinitionFormatYAML:
+		if err := yaml.UnmarshalStrict(data, definition); err != nil {
+			return nil, err
+		}
+	case DefinitionFormatJSON:
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(definition); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.New("survey definition format must be json or yaml")
+	}
+
+	if definition.Version < 1 || definition.Version > DefinitionVersion {
+		return nil, fmt.Errorf("unsupported survey definition version %d, this release reads version %d", definition.Version, DefinitionVersion)
+	}
+
+	return definition, nil
+}
+
+// Encode writes the definition document in the given format.
+func (d *SurveyDefinition) Encode(format DefinitionFormat) ([]byte, error) {
+	switch format {
+	case DefinitionFormatYAML:
+		return yaml.Marshal(d)
+	case DefinitionFormatJSON:
+		return json.MarshalIndent(d, "", "  ")
+	}
+
+	return nil, errors.New("survey definition format must be json or yaml")
+}
+
+// NewSurveyDefinition describes a survey with its questions, choices, conditions,
+// eligibility and quotas loaded.
+func NewSurveyDefinition(survey *Survey) *SurveyDefinition {
+	definition := &SurveyDefinition{
+		Version:       DefinitionVersion,
+		Subject:       survey.Subject,
+		Description:   survey.Description,
+		DateStart:     survey.DateStart.UTC(),
+		DateEnd:       survey.DateEnd.UTC(),
+		Sensitive:     survey.Sensitive,
+		Audience:      survey.Audience,
+		MinCellSize:   survey.MinCellSize,
+		Noise:         survey.Noise,
+		PrivacyBudget: survey.PrivacyBudget,
+		Epsilon:       survey.Epsilon,
+		BallotChanges: survey.BallotChanges,
+		Anonymous:     survey.Anonymous,
+		Private:       survey.Private,
+		RespondentCap: survey.RespondentCap,
+		Questions:     make([]QuestionDefinition, 0, len(survey.Questions)),
+	}
+
+	eligibility := survey.Eligibility
+	if eligibility.ResidentsOnly || eligibility.MinAge != nil || eligibility.MaxAge != nil || len(eligibility.BirthSexes) > 0 || len(eligibility.GenderIdentities) > 0 {
+		definition.Eligibility = &EligibilityDefinition{
+			ResidentsOnly:    eligibility.ResidentsOnly,
+			MinAge:           eligibility.MinAge,
+			MaxAge:           eligibility.MaxAge,
+			BirthSexes:       eligibility.BirthSexes,
+			GenderIdentities: eligibility.GenderIdentities,
+		}
+	}
+
+	for _, quota := range survey.Quotas {
+		definition.Quotas = append(definition.Quotas, QuotaDefinition{
+			BirthSex:       quota.BirthSex,
+			GenderIdentity: quota.GenderIdentity,
+			MinAge:         quota.MinAge,
+			MaxAge:         quota.MaxAge,
+			IsResident:     quota.IsResident,
+			Target:         quota.Target,
+		})
+	}
+
+	for i, question := range survey.Questions {
+		questionDefinition := QuestionDefinition{
+			Key:        definitionKey(i),
+			Text:       question.Value,
+			Type:       question.Type,
+			MinChoices: question.MinChoices,
+			MaxChoices: question.MaxChoices,
+			MinNumber:  question.MinNumber,
+			MaxNumber:  question.MaxNumber,
+		}
+		for _, choice := range question.Choices {
+			questionDefinition.Choices = append(questionDefinition.Choices, choice.Value)
+		}
+		for _, condition := range question.Conditions {
+			source := survey.Questions[condition.SourceIndex]
+			questionDefinition.ShowIf = append(questionDefinition.ShowIf, ConditionDefinition{
+				Question: definitionKey(int(condition.SourceIndex)),
+				Choice:   source.Choices[condition.ChoiceIndex].Value,
+			})
+		}
+		definition.Questions = append(definition.Questions, questionDefinition)
+	}
+
+	return definition
+}
+
+// definitionKey is the default key of the question at a position
+func definitionKey(position int) string {
+	return "q" + strconv.Itoa(position+1)
+}
+
+// Survey builds the survey of the definition, conditions are resolved to the
+// positions of their questions and choices. The survey still has to be validated.
+func (d *SurveyDefinition) Survey() (*Survey, error) {
+	if d.Subject == "" || d.Description == "" {
+		return nil, errors.New("survey definition needs a subject and a description")
+	}
+
+	survey := &Survey{
+		Subject:       d.Subject,
+		Description:   d.Description,
+		DateStart:     d.DateStart,
+		DateEnd:       d.DateEnd,
+		Sensitive:     d.Sensitive,
+		Audience:      d.Audience,
+		MinCellSize:   d.MinCellSize,
+		Noise:         d.Noise,
+		PrivacyBudget: d.PrivacyBudget,
+		Epsilon:       d.Epsilon,
+		BallotChanges: d.BallotChanges,
+		Anonymous:     d.Anonymous,
+		Private:       d.Private,
+		RespondentCap: d.RespondentCap,
+		Questions:     make([]Question, 0, len(d.Questions)),
+	}
+	if d.Eligibility != nil {
+		survey.Eligibility = Eligibility{
+			ResidentsOnly:    d.Eligibility.ResidentsOnly,
+			MinAge:           d.Eligibility.MinAge,
+			MaxAge:           d.Eligibility.MaxAge,
+			BirthSexes:       d.Eligibility.BirthSexes,
+			GenderIdentities: d.Eligibility.GenderIdentities,
+		}
+	}
+
+	for _, quota := range d.Quotas {
+		survey.Quotas = append(survey.Quotas, Quota{
+			BirthSex:       quota.BirthSex,
+			GenderIdentity: quota.GenderIdentity,
+			MinAge:         quota.MinAge,
+			MaxAge:         quota.MaxAge,
+			IsResident:     quota.IsResident,
+			Target:         quota.Target,
+		})
+	}
+
+	positions := make(map[string]int)
+	for i, questionDefinition := range d.Questions {
+		key := questionDefinition.Key
+		if key == "" {
+			key = definitionKey(i)
+		}
+		if _, ok := positions[key]; ok {
+			return nil, fmt.Errorf("question key %q is used more than once", key)
+		}
+		positions[key] = i
+
+		if questionDefinition.Text == "" {
+			return nil, fmt.Errorf("question %q needs a text", key)
+		}
+
+		question := Question{
+			Value:      questionDefinition.Text,
+			Type:       questionDefinition.Type,
+			MinChoices: questionDefinition.MinChoices,
+			MaxChoices: questionDefinition.MaxChoices,
+			MinNumber:  questionDefinition.MinNumber,
+			MaxNumber:  questionDefinition.MaxNumber,
+			Choices:    make([]Choice, 0, len(questionDefinition.Choices)),
+		}
+		for _, choice := range questionDefinition.Choices {
+			question.Choices = append(question.Choices, Choice{Value: choice})
+		}
+		survey.Questions = append(survey.Questions, question)
+	}
+
+	for i, questionDefinition := range d.Questions {
+		for _, showIf := range questionDefinition.ShowIf {
+			source, ok := positions[showIf.Question]
+			if !ok {
+				return nil, fmt.Errorf("condition refers to the unknown question %q", showIf.Question)
+			}
+
+			choice := -1
+			for j, value := range d.Questions[source].Choices {
+				if value == showIf.Choice {
+					choice = j
+					break
+				}
+			}
+			if choice < 0 {
+				return nil, fmt.Errorf("condition refers to the unknown choice %q of question %q", showIf.Choice, showIf.Question)
+			}
+
+			survey.Questions[i].Conditions = append(survey.Questions[i].Conditions, Condition{SourceIndex: uint(source), ChoiceIndex: uint(choice)})
+		}
+	}
+
+	return survey, nil
+}
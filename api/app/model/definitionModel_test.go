@@ -0,0 +1,117 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+const yamlDefinition = `
+version: 1
+subject: Commute
+description: How do you get to work?
+date_start: 2022-05-01T00:00:00Z
+date_end: 2022-06-01T00:00:00Z
+eligibility:
+  min_age: 18
+quotas:
+  - birth_sex: woman
+    target: 50
+questions:
+  - key: mode
+    text: How do you commute?
+    choices: [Car, Bike, Walk]
+  - text: How long is the ride in minutes?
+    type: numeric
+    min_number: 0
+    show_if:
+      - question: mode
+        choice: Bike
+`
+
+func TestParseSurveyDefinition(t *testing.T) {
+	definition, err := ParseSurveyDefinition([]byte(yamlDefinition), DefinitionFormatYAML)
+	if err != nil {
+		t.Fatalf("ParseSurveyDefinition() error = %v", err)
+	}
+
+	survey, err := definition.Survey()
+	if err != nil {
+		t.Fatalf("Survey() error = %v", err)
+	}
+	if err = survey.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	if !survey.DateStart.Equal(time.Date(2022, 5, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("DateStart = %v", survey.DateStart)
+	}
+	if survey.Eligibility.MinAge == nil || *survey.Eligibility.MinAge != 18 || len(survey.Quotas) != 1 {
+		t.Errorf("eligibility and quotas not imported: %+v %+v", survey.Eligibility, survey.Quotas)
+	}
+	want := []Condition{{SourceIndex: 0, ChoiceIndex: 1}}
+	if got := survey.Questions[1].Conditions; !reflect.DeepEqual(got, want) {
+		t.Errorf("Conditions = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseSurveyDefinitionErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		format DefinitionFormat
+	}{
+		{"unknown field", `{"version": 1, "subjekt": "s"}`, DefinitionFormatJSON},
+		{"no version", `{"subject": "s"}`, DefinitionFormatJSON},
+		{"later version", "version: 2\nsubject: s", DefinitionFormatYAML},
+		{"unknown format", `{"version": 1}`, "xml"},
+	}
+	for _, tt := range tests {
+		if _, err := ParseSurveyDefinition([]byte(tt.data), tt.format); err == nil {
+			t.Errorf("%s: ParseSurveyDefinition() succeeded", tt.name)
+		}
+	}
+}
+
+func TestSurveyDefinitionConditionErrors(t *testing.T) {
+	questions := [][]QuestionDefinition{
+		{{Text: "a", Choices: []string{"x"}}, {Text: "b", ShowIf: []ConditionDefinition{{Question: "q3", Choice: "x"}}}},
+		{{Text: "a", Choices: []string{"x"}}, {Text: "b", ShowIf: []ConditionDefinition{{Question: "q1", Choice: "y"}}}},
+		{{Key: "a", Text: "a"}, {Key: "a", Text: "b"}},
+	}
+	for _, q := range questions {
+		definition := SurveyDefinition{Version: 1, Subject: "s", Description: "d", Questions: q}
+		if _, err := definition.Survey(); err == nil {
+			t.Errorf("Survey(%+v) succeeded", q)
+		}
+	}
+}
+
+func TestSurveyDefinitionRoundTrip(t *testing.T) {
+	definition, err := ParseSurveyDefinition([]byte(yamlDefinition), DefinitionFormatYAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	survey, err := definition.Survey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, format := range []DefinitionFormat{DefinitionFormatJSON, DefinitionFormatYAML} {
+		data, err := NewSurveyDefinition(survey).Encode(format)
+		if err != nil {
+			t.Fatalf("%s: Encode() error = %v", format, err)
+		}
+		parsed, err := ParseSurveyDefinition(data, format)
+		if err != nil {
+			t.Fatalf("%s: ParseSurveyDefinition() error = %v\n%s", format, err, data)
+		}
+		again, err := parsed.Survey()
+		if err != nil {
+			t.Fatalf("%s: Survey() error = %v", format, err)
+		}
+		if !reflect.DeepEqual(again, survey) {
+			t.Errorf("%s: round trip = %+v, want %+v", format, again, survey)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// DraftBallot keeps the partial answers of a user to an active survey until they
+// submit them as a vote. Drafts are never tallied and expire when the survey closes.
+type DraftBallot struct {
+	UserRefer   uint     `gorm:"primaryKey;autoIncrement:false" json:"-"`
+	SurveyRefer uint     `gorm:"primaryKey;autoIncrement:false"`
+	Content     string   `json:"-"` // answers as JSON
+	Answers     []Answer `gorm:"-"`
+	UpdatedAt   time.Time
+	ExpiresAt   time.Time `gorm:"-"` // end of the survey
+}
+
+// NewDraftBallot stores the partial answers of a user.
+func NewDraftBallot(userID, surveyID uint, answers []Answer) (*DraftBallot, error) {
+	content, err := json.Marshal(answers)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DraftBallot{UserRefer: userID, SurveyRefer: surveyID, Content: string(content), Answers: answers}, nil
+}
+
+// Decode restores the answers of a stored draft.
+func (d *DraftBallot) Decode() error {
+	d.Answers = make([]Answer, 0)
+	if d.Content == "" {
+		return nil
+	}
+
+	return json.Unmarshal([]byte(d.Content), &d.Answers)
+}
+
+// ValidateDraftAnswers checks partial answers before they are kept. Answers are only
+// checked against their questions and conditions once the draft is submitted.
+func (s *Survey) ValidateDraftAnswers(answers []Answer) error {
+	answered := make(map[uint]bool)
+	for _, answer := range answers {
+		if s.Question(answer.QuestionID) == nil {
+			return errors.New("question does not belong to this survey")
+		}
+		if answered[answer.QuestionID] {
+			return errors.New("question answered more than once")
+		}
+		answered[answer.QuestionID] = true
+	}
+
+	return nil
+}
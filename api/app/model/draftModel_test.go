@@ -0,0 +1,39 @@
+package model
+
+import (
+	"testing"
+)
+
+func TestDraftBallotDecode(t *testing.T) {
+	number := 3.5
+	answers := []Answer{{QuestionID: 1, Choices: []uint{2, 0}}, {QuestionID: 2, Number: &number}}
+
+	draft, err := NewDraftBallot(7, 4, answers)
+	if err != nil {
+		t.Fatalf("NewDraftBallot() error = %v", err)
+	}
+
+	stored := DraftBallot{UserRefer: draft.UserRefer, SurveyRefer: draft.SurveyRefer, Content: draft.Content}
+	if err = stored.Decode(); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if len(stored.Answers) != 2 || stored.Answers[0].Choices[0] != 2 || *stored.Answers[1].Number != number {
+		t.Errorf("Decode() = %+v, want %+v", stored.Answers, answers)
+	}
+}
+
+func TestValidateDraftAnswers(t *testing.T) {
+	survey := Survey{Questions: []Question{{SurveyRefer: 1, Type: QuestionTypeSingle}, {SurveyRefer: 1, Type: QuestionTypeNumeric}}}
+	survey.Questions[0].ID, survey.Questions[1].ID = 1, 2
+
+	// partial answers are kept without checking their choices
+	if err := survey.ValidateDraftAnswers([]Answer{{QuestionID: 2}}); err != nil {
+		t.Errorf("ValidateDraftAnswers() error = %v", err)
+	}
+	if err := survey.ValidateDraftAnswers([]Answer{{QuestionID: 3}}); err == nil {
+		t.Error("ValidateDraftAnswers() accepted a question of another survey")
+	}
+	if err := survey.ValidateDraftAnswers([]Answer{{QuestionID: 1}, {QuestionID: 1}}); err == nil {
+		t.Error("ValidateDraftAnswers() accepted a question answered twice")
+	}
+}
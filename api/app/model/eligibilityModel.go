@@ -0,0 +1,161 @@
+package model
+
+import (
+	"errors"
+	"time"
+)
+
+// Eligibility restricts who may vote in a survey, the zero value admits every
+// registered user. Empty sets do not restrict their attribute.
+type Eligibility struct {
+	ResidentsOnly    bool             `fake:"skip"`
+	MinAge           *uint            `json:",omitempty" fake:"skip"`
+	MaxAge           *uint            `json:",omitempty" fake:"skip"`
+	BirthSexes       []BirthSex       `gorm:"-" json:",omitempty" fake:"skip"`
+	GenderIdentities []GenderIdentity `gorm:"-" json:",omitempty" fake:"skip"`
+	Invitees         []string         `gorm:"-" json:",omitempty" fake:"skip"` // IDNumbers of the invited users
+}
+
+type EligibilityAttribute string
+
+const (
+	EligibilityAttributeBirthSex       EligibilityAttribute = "birthSex"
+	EligibilityAttributeGenderIdentity EligibilityAttribute = "genderIdentity"
+	EligibilityAttributeInvitee        EligibilityAttribute = "invitee"
+)
+
+// EligibleValue stores one member of a set valued eligibility criterion.
+type EligibleValue struct {
+	SurveyRefer uint                 `gorm:"primaryKey;autoIncrement:false"`
+	Attribute   EligibilityAttribute `gorm:"primaryKey"`
+	Value       string               `gorm:"primaryKey"`
+}
+
+// Age returns the age in whole years of someone born on birthDate at the given time.
+func Age(birthDate, at time.Time) int {
+	age := at.Year() - birthDate.Year()
+	if at.Format("01-02") < birthDate.Format("01-02") {
+		age--
+	}
+
+	return age
+}
+
+// Restricted reports whether any criterion is set.
+func (e *Eligibility) Restricted() bool {
+	return e.ResidentsOnly || e.MinAge != nil || e.MaxAge != nil || len(e.BirthSexes) > 0 || len(e.GenderIdentities) > 0 || len(e.Invitees) > 0
+}
+
+// Validate rejects criteria no user can meet.
+func (e *Eligibility) Validate() error {
+	if e.MinAge != nil && e.MaxAge != nil && *e.MinAge > *e.MaxAge {
+		return errors.New("minimum age can not be above the maximum age")
+	}
+
+	for _, birthSex := range e.BirthSexes {
+		switch birthSex {
+		case BirthSexWoman, BirthSexMan, BirthSexNoRespond:
+		default:
+			return errors.New("eligibility refers to an unknown birth sex")
+		}
+	}
+	for _, genderIdentity := range e.GenderIdentities {
+		switch genderIdentity {
+		case GenderIdentityWoman, GenderIdentityMan, GenderIdentityTransgender, GenderIdentityNonBin, GenderIdentityNoRespond:
+		default:
+			return errors.New("eligibility refers to an unknown gender identity")
+		}
+	}
+	for _, invitee := range e.Invitees {
+		if len(invitee) != 11 {
+			return errors.New("invitees must be given by their 11 digit IDNumber")
+		}
+	}
+
+	return nil
+}
+
+// Admits reports whether the user meets every criterion at the given time.
+func (e *Eligibility) Admits(user *User, at time.Time) bool {
+	if e.ResidentsOnly && (user.IsResident == nil || !*user.IsResident) {
+		return false
+	}
+
+	age := Age(user.BirthDate, at)
+	if e.MinAge != nil && age < int(*e.MinAge) {
+		return false
+	}
+	if e.MaxAge != nil && age > int(*e.MaxAge) {
+		return false
+	}
+
+	if len(e.BirthSexes) > 0 && !containsBirthSex(e.BirthSexes, user.BirthSex) {
+		return false
+	}
+	if len(e.GenderIdentities) > 0 && !containsGenderIdentity(e.GenderIdentities, user.GenderIdentity) {
+		return false
+	}
+	if len(e.Invitees) > 0 && !containsString(e.Invitees, user.IDNumber) {
+		return false
+	}
+
+	return true
+}
+
+// Values lists the set valued criteria for storage.
+func (e *Eligibility) Values(surveyID uint) []EligibleValue {
+	values := make([]EligibleValue, 0)
+	for _, birthSex := range e.BirthSexes {
+		values = append(values, EligibleValue{surveyID, EligibilityAttributeBirthSex, string(birthSex)})
+	}
+	for _, genderIdentity := range e.GenderIdentities {
+		values = append(values, EligibleValue{surveyID, EligibilityAttributeGenderIdentity, string(genderIdentity)})
+	}
+	for _, invitee := range e.Invitees {
+		values = append(values, EligibleValue{surveyID, EligibilityAttributeInvitee, invitee})
+	}
+
+	return values
+}
+
+// AddValue restores a stored member of a set valued criterion.
+func (e *Eligibility) AddValue(value EligibleValue) {
+	switch value.Attribute {
+	case EligibilityAttributeBirthSex:
+		e.BirthSexes = append(e.BirthSexes, BirthSex(value.Value))
+	case EligibilityAttributeGenderIdentity:
+		e.GenderIdentities = append(e.GenderIdentities, GenderIdentity(value.Value))
+	case EligibilityAttributeInvitee:
+		e.Invitees = append(e.Invitees, value.Value)
+	}
+}
+
+func containsBirthSex(set []BirthSex, value BirthSex) bool {
+	for _, member := range set {
+		if member == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsGenderIdentity(set []GenderIdentity, value GenderIdentity) bool {
+	for _, member := range set {
+		if member == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsString(set []string, value string) bool {
+	for _, member := range set {
+		if member == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,78 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEligibilityAdmits(t *testing.T) {
+	at := time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)
+	resident, visitor := true, false
+	eighteen, sixtyFour := uint(18), uint(64)
+	user := &User{
+		IDNumber:       "12345678901",
+		BirthSex:       BirthSexWoman,
+		GenderIdentity: GenderIdentityNonBin,
+		BirthDate:      time.Date(2004, 6, 1, 0, 0, 0, 0, time.UTC),
+		IsResident:     &resident,
+	}
+
+	tests := []struct {
+		name        string
+		eligibility Eligibility
+		user        User
+		want        bool
+	}{
+		{"everyone", Eligibility{}, *user, true},
+		{"resident", Eligibility{ResidentsOnly: true}, *user, true},
+		{"visitor", Eligibility{ResidentsOnly: true}, User{IsResident: &visitor}, false},
+		{"turned 18 today", Eligibility{MinAge: &eighteen}, *user, true},
+		{"too young", Eligibility{MinAge: &eighteen}, User{BirthDate: time.Date(2004, 6, 2, 0, 0, 0, 0, time.UTC)}, false},
+		{"too old", Eligibility{MaxAge: &sixtyFour}, User{BirthDate: time.Date(1957, 6, 1, 0, 0, 0, 0, time.UTC)}, false},
+		{"birth sex", Eligibility{BirthSexes: []BirthSex{BirthSexMan, BirthSexWoman}}, *user, true},
+		{"other birth sex", Eligibility{BirthSexes: []BirthSex{BirthSexMan}}, *user, false},
+		{"other gender identity", Eligibility{GenderIdentities: []GenderIdentity{GenderIdentityWoman}}, *user, false},
+		{"invited", Eligibility{Invitees: []string{"12345678901"}}, *user, true},
+		{"not invited", Eligibility{Invitees: []string{"10987654321"}}, *user, false},
+	}
+	for _, tt := range tests {
+		if got := tt.eligibility.Admits(&tt.user, at); got != tt.want {
+			t.Errorf("%s: Admits() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestEligibilityValidate(t *testing.T) {
+	eighteen, twelve := uint(18), uint(12)
+	invalid := []Eligibility{
+		{MinAge: &eighteen, MaxAge: &twelve},
+		{BirthSexes: []BirthSex{"other"}},
+		{GenderIdentities: []GenderIdentity{"other"}},
+		{Invitees: []string{"123"}},
+	}
+	for _, eligibility := range invalid {
+		if err := eligibility.Validate(); err == nil {
+			t.Errorf("Validate(%+v) succeeded", eligibility)
+		}
+	}
+
+	valid := Eligibility{MinAge: &twelve, MaxAge: &eighteen, BirthSexes: []BirthSex{BirthSexNoRespond}}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Validate(%+v) error = %v", valid, err)
+	}
+}
+
+func TestEligibilityValues(t *testing.T) {
+	eligibility := Eligibility{BirthSexes: []BirthSex{BirthSexMan}, GenderIdentities: []GenderIdentity{GenderIdentityMan}, Invitees: []string{"12345678901"}}
+
+	restored := Eligibility{}
+	for _, value := range eligibility.Values(3) {
+		if value.SurveyRefer != 3 {
+			t.Errorf("Values() survey = %d, want 3", value.SurveyRefer)
+		}
+		restored.AddValue(value)
+	}
+	if len(restored.BirthSexes) != 1 || len(restored.GenderIdentities) != 1 || len(restored.Invitees) != 1 || !restored.Restricted() {
+		t.Errorf("AddValue() restored %+v, want %+v", restored, eligibility)
+	}
+}
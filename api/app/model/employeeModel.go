@@ -8,4 +8,5 @@ import (
 type Employee struct {
 	gorm.Model
 	UserRefer uint
+	Senior    bool // may approve sensitive and large audience surveys
 }
@@ -0,0 +1,263 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// ExportFormat is the file format of a survey export.
+type ExportFormat string
+
+const (
+	ExportFormatCSV    ExportFormat = "csv"
+	ExportFormatXLSX   ExportFormat = "xlsx"
+	ExportFormatNDJSON ExportFormat = "ndjson" // one JSON object per row
+)
+
+// Validate rejects unknown export formats.
+func (f ExportFormat) Validate() error {
+	switch f {
+	case ExportFormatCSV, ExportFormatXLSX, ExportFormatNDJSON:
+		return nil
+	}
+
+	return errors.New("export format must be csv, xlsx or ndjson")
+}
+
+// ContentType returns the media type of the format.
+func (f ExportFormat) ContentType() string {
+	switch f {
+	case ExportFormatXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	case ExportFormatNDJSON:
+		return "application/x-ndjson"
+	}
+
+	return "text/csv; charset=utf-8"
+}
+
+// SuppressedDemographic replaces the demographics of respondents whose demographic
+// group is too small to be disclosed.
+const SuppressedDemographic = "suppressed"
+
+// ResultColumns names the columns of the results export, one row per choice or per
+// numeric and text question.
+var ResultColumns = []string{"question_id", "question", "type", "choice_id", "choice", "votes", "percentage", "mean_rank", "respondents", "mean", "min", "max"}
+
+// ResultRows flattens the summarized results of a survey into rows of ResultColumns.
+func ResultRows(survey *Survey) [][]interface{} {
+	rows := make([][]interface{}, 0)
+	for i := range survey.Questions {
+		question := &survey.Questions[i]
+		summary := question.Summary
+		if summary == nil {
+			summary = &Summary{}
+		}
+
+		if !question.HasChoices() {
+			rows = append(rows, []interface{}{question.ID, question.Value, question.Type, nil, nil, summary.Count, nil, nil,
+				summary.Respondents, optionalFloat(summary.Mean), optionalFloat(summary.Min), optionalFloat(summary.Max)})
+			continue
+		}
+
+		for _, choice := range question.Choices {
+			rows = append(rows, []interface{}{question.ID, question.Value, question.Type, choice.ID, choice.Value, choice.VoteCount, choice.Percentage, optionalFloat(choice.MeanRank),
+				summary.Respondents, optionalFloat(summary.Mean), nil, nil})
+		}
+	}
+
+	return rows
+}
+
+func optionalFloat(value *float64) interface{} {
+	if value == nil {
+		return nil
+	}
+
+	return *value
+}
+
+// ResponseRow is one stored vote as streamed from the store, ordered by respondent.
+// User only holds the demographics of the voter and is nil on anonymous ballots.
+type ResponseRow struct {
+	Respondent string // ballot key, or the voter of ballots cast before the ballot log
+	QuestionID uint
+	ChoiceID   *uint
+	Rank       uint
+	Number     *float64
+	Text       *string
+	User       *User
+}
+
+// ResponseMatrix turns the streamed votes of a survey into one row per respondent
+// and one column per question. The demographic columns are only disclosed for
+// respondents whose combined demographic group has at least minCellSize respondents.
+type ResponseMatrix struct {
+	survey       *Survey
+	demographics bool
+	minCellSize  int
+	groups       map[string]int
+	codebook     *Codebook // codes the answers instead of labelling them
+
+	respondents int
+	current     string
+	user        *User
+	answers     map[uint][]ResponseRow
+}
+
+// NewResponseMatrix prepares the matrix of a survey with its questions and choices.
+// Anonymous surveys have no demographic columns.
+func NewResponseMatrix(survey *Survey, minCellSize int) *ResponseMatrix {
+	return &ResponseMatrix{
+		survey:       survey,
+		demographics: !survey.Anonymous,
+		minCellSize:  minCellSize,
+		groups:       make(map[string]int),
+		answers:      make(map[uint][]ResponseRow),
+	}
+}
+
+// NewCodedResponseMatrix prepares the matrix of a survey coded after its codebook.
+func NewCodedResponseMatrix(survey *Survey, minCellSize int) *ResponseMatrix {
+	m := NewResponseMatrix(survey, minCellSize)
+	m.codebook = NewCodebook(survey, minCellSize)
+
+	return m
+}
+
+// CountRespondent adds a respondent to its demographic group, every respondent must
+// be counted before the first row is added.
+func (m *ResponseMatrix) CountRespondent(user *User) {
+	m.groups[m.groupKey(user)]++
+}
+
+func (m *ResponseMatrix) groupKey(user *User) string {
+	groups := VoterGroups(user, m.survey.DateEnd)
+
+	return fmt.Sprintf("%s|%s|%s|%s", groups[CrosstabDimensionBirthSex], groups[CrosstabDimensionGenderIdentity], groups[CrosstabDimensionAgeBand], groups[CrosstabDimensionIsResident])
+}
+
+// Columns names the columns of the matrix, questions are named q<question id>.
+func (m *ResponseMatrix) Columns() []string {
+	if m.codebook != nil {
+		return m.codebook.Columns()
+	}
+
+	columns := []string{"respondent"}
+	if m.demographics {
+		columns = append(columns, "birth_sex", "gender_identity", "age_band", "is_resident")
+	}
+	for _, question := range m.survey.Questions {
+		columns = append(columns, "q"+strconv.FormatUint(uint64(question.ID), 10))
+	}
+
+	return columns
+}
+
+// Add collects a vote of the current respondent. When the vote starts a new
+// respondent the row of the previous one is returned.
+func (m *ResponseMatrix) Add(row ResponseRow) (complete []interface{}) {
+	if row.Respondent != m.current {
+		complete = m.Flush()
+		m.current = row.Respondent
+		m.user = row.User
+	}
+
+	m.answers[row.QuestionID] = append(m.answers[row.QuestionID], row)
+
+	return complete
+}
+
+// Flush returns the row of the current respondent, nil if no vote was added since.
+func (m *ResponseMatrix) Flush() []interface{} {
+	if len(m.answers) == 0 {
+		return nil
+	}
+
+	m.respondents++
+	var row []interface{}
+	if m.codebook != nil {
+		row = m.codebook.cells(m.respondents, m.disclosedUser(), m.answers)
+	} else {
+		row = []interface{}{m.respondents}
+		if m.demographics {
+			row = append(row, m.demographicCells()...)
+		}
+		for i := range m.survey.Questions {
+			row = append(row, m.answerCell(&m.survey.Questions[i], m.answers[m.survey.Questions[i].ID]))
+		}
+	}
+
+	m.answers = make(map[uint][]ResponseRow)
+	m.user = nil
+
+	return row
+}
+
+// disclosedUser returns the voter of the current respondent if their demographic
+// group is large enough to be disclosed
+func (m *ResponseMatrix) disclosedUser() *User {
+	if !m.demographics || m.user == nil || m.groups[m.groupKey(m.user)] < m.minCellSize {
+		return nil
+	}
+
+	return m.user
+}
+
+func (m *ResponseMatrix) demographicCells() []interface{} {
+	user := m.disclosedUser()
+	if user == nil {
+		return []interface{}{SuppressedDemographic, SuppressedDemographic, SuppressedDemographic, SuppressedDemographic}
+	}
+
+	groups := VoterGroups(user, m.survey.DateEnd)
+
+	return []interface{}{groups[CrosstabDimensionBirthSex], groups[CrosstabDimensionGenderIdentity], groups[CrosstabDimensionAgeBand], groups[CrosstabDimensionIsResident]}
+}
+
+// answerCell renders the answer of a respondent to a question: the choice of single
+// and likert questions, the choices of multi-select questions, the choices of ranking
+// questions in order of preference, or the number or text
+func (m *ResponseMatrix) answerCell(question *Question, votes []ResponseRow) interface{} {
+	if len(votes) == 0 {
+		return nil
+	}
+
+	switch question.Type {
+	case QuestionTypeNumeric:
+		return optionalFloat(votes[0].Number)
+	case QuestionTypeText:
+		if votes[0].Text == nil {
+			return nil
+		}
+		return *votes[0].Text
+	}
+
+	if question.Type == QuestionTypeRanking {
+		sort.SliceStable(votes, func(i, j int) bool { return votes[i].Rank < votes[j].Rank })
+	}
+
+	values := make([]string, 0, len(votes))
+	for _, vote := range votes {
+		if vote.ChoiceID == nil {
+			continue
+		}
+		for _, choice := range question.Choices {
+			if choice.ID == *vote.ChoiceID {
+				values = append(values, choice.Value)
+			}
+		}
+	}
+
+	switch question.Type {
+	case QuestionTypeMulti, QuestionTypeRanking:
+		return values
+	}
+	if len(values) == 0 {
+		return nil
+	}
+
+	return values[0]
+}
@@ -0,0 +1,98 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func exportSurvey() *Survey {
+	survey := &Survey{DateEnd: time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)}
+	survey.Questions = []Question{
+		{Type: QuestionTypeSingle, Choices: []Choice{{Value: "yes"}, {Value: "no"}}},
+		{Type: QuestionTypeRanking, Choices: []Choice{{Value: "a"}, {Value: "b"}}},
+		{Type: QuestionTypeText},
+	}
+	survey.Questions[0].ID, survey.Questions[1].ID, survey.Questions[2].ID = 1, 2, 3
+	survey.Questions[0].Choices[0].ID, survey.Questions[0].Choices[1].ID = 10, 11
+	survey.Questions[1].Choices[0].ID, survey.Questions[1].Choices[1].ID = 20, 21
+
+	return survey
+}
+
+func TestResponseMatrix(t *testing.T) {
+	survey := exportSurvey()
+	woman := &User{BirthSex: BirthSexWoman, GenderIdentity: GenderIdentityWoman, BirthDate: time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)}
+	man := &User{BirthSex: BirthSexMan, GenderIdentity: GenderIdentityMan, BirthDate: time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+	matrix := NewResponseMatrix(survey, 2)
+	matrix.CountRespondent(woman)
+	matrix.CountRespondent(woman)
+	matrix.CountRespondent(man)
+
+	wantColumns := []string{"respondent", "birth_sex", "gender_identity", "age_band", "is_resident", "q1", "q2", "q3"}
+	if got := matrix.Columns(); !reflect.DeepEqual(got, wantColumns) {
+		t.Fatalf("Columns() = %v, want %v", got, wantColumns)
+	}
+
+	yes, a, b := uint(10), uint(20), uint(21)
+	text := "fine"
+	votes := []ResponseRow{
+		{Respondent: "k1", QuestionID: 1, ChoiceID: &yes, User: woman},
+		{Respondent: "k1", QuestionID: 2, ChoiceID: &a, Rank: 2, User: woman},
+		{Respondent: "k1", QuestionID: 2, ChoiceID: &b, Rank: 1, User: woman},
+		{Respondent: "k2", QuestionID: 3, Text: &text, User: man},
+	}
+
+	rows := make([][]interface{}, 0)
+	for _, vote := range votes {
+		if row := matrix.Add(vote); row != nil {
+			rows = append(rows, row)
+		}
+	}
+	rows = append(rows, matrix.Flush())
+
+	want := [][]interface{}{
+		{1, "woman", "woman", "18-24", "false", "yes", []string{"b", "a"}, nil},
+		{2, SuppressedDemographic, SuppressedDemographic, SuppressedDemographic, SuppressedDemographic, nil, nil, "fine"},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("rows = %v, want %v", rows, want)
+	}
+	if row := matrix.Flush(); row != nil {
+		t.Errorf("Flush() after the last respondent = %v", row)
+	}
+}
+
+func TestResponseMatrixAnonymous(t *testing.T) {
+	survey := exportSurvey()
+	survey.Anonymous = true
+
+	matrix := NewResponseMatrix(survey, 1)
+	want := []string{"respondent", "q1", "q2", "q3"}
+	if got := matrix.Columns(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Columns() = %v, want %v", got, want)
+	}
+}
+
+func TestResultRows(t *testing.T) {
+	survey := exportSurvey()
+	survey.Questions[0].Choices[0].VoteCount = 3
+	survey.Questions[2].Summary = &Summary{Count: 2, Respondents: 2}
+
+	rows := ResultRows(survey)
+	if len(rows) != 5 {
+		t.Fatalf("ResultRows() returned %d rows, want 5", len(rows))
+	}
+	for _, row := range rows {
+		if len(row) != len(ResultColumns) {
+			t.Fatalf("row %v has %d cells, want %d", row, len(row), len(ResultColumns))
+		}
+	}
+	if rows[0][4] != "yes" || rows[0][5] != 3 {
+		t.Errorf("first row = %v, want the votes of the first choice", rows[0])
+	}
+	if rows[4][3] != nil || rows[4][5] != 2 {
+		t.Errorf("text row = %v, want the answer count without a choice", rows[4])
+	}
+}
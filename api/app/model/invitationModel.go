@@ -0,0 +1,55 @@
+package model
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Invitation admits voters to a private survey. The token is shared with the
+// invited voters, every first ballot redeems one use of it.
+type Invitation struct {
+	gorm.Model
+	SurveyRefer uint   `gorm:"index"`
+	Token       string `gorm:"uniqueIndex"`
+	MaxUses     uint   // 1 for single use, 0 for unlimited uses
+	Uses        uint
+	RevokedAt   *time.Time `json:",omitempty"`
+}
+
+// Redemption records the voter that redeemed an invitation.
+type Redemption struct {
+	InvitationRefer uint `gorm:"primaryKey;autoIncrement:false"`
+	UserRefer       uint `gorm:"primaryKey;autoIncrement:false"`
+	CreatedAt       time.Time
+}
+
+// InvitationRequest asks for a batch of invitations to a survey.
+type InvitationRequest struct {
+	Count   uint `binding:"required"`
+	MaxUses uint // 1 for single use, 0 for unlimited uses
+}
+
+// MaxInvitationBatch limits the invitations created at once.
+const MaxInvitationBatch = 1000
+
+// NewInvitations creates the requested invitations with random tokens.
+func NewInvitations(surveyID uint, request InvitationRequest) ([]Invitation, error) {
+	if request.Count == 0 || request.Count > MaxInvitationBatch {
+		return nil, errors.New("invitations must be created in batches of 1 to 1000")
+	}
+
+	invitations := make([]Invitation, 0, request.Count)
+	for i := uint(0); i < request.Count; i++ {
+		token := make([]byte, 16)
+		if _, err := rand.Read(token); err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, Invitation{SurveyRefer: surveyID, Token: hex.EncodeToString(token), MaxUses: request.MaxUses})
+	}
+
+	return invitations, nil
+}
@@ -0,0 +1,32 @@
+package model
+
+import (
+	"testing"
+)
+
+func TestNewInvitations(t *testing.T) {
+	invitations, err := NewInvitations(4, InvitationRequest{Count: 3, MaxUses: 1})
+	if err != nil {
+		t.Fatalf("NewInvitations() error = %v", err)
+	}
+	if len(invitations) != 3 {
+		t.Fatalf("NewInvitations() created %d invitations, want 3", len(invitations))
+	}
+
+	tokens := make(map[string]bool)
+	for _, invitation := range invitations {
+		if invitation.SurveyRefer != 4 || invitation.MaxUses != 1 || len(invitation.Token) != 32 {
+			t.Errorf("NewInvitations() = %+v", invitation)
+		}
+		tokens[invitation.Token] = true
+	}
+	if len(tokens) != 3 {
+		t.Errorf("NewInvitations() repeated tokens %v", tokens)
+	}
+
+	for _, count := range []uint{0, MaxInvitationBatch + 1} {
+		if _, err := NewInvitations(4, InvitationRequest{Count: count}); err == nil {
+			t.Errorf("NewInvitations(%d) succeeded", count)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package model
+
+import (
+	"gorm.io/gorm"
+)
+
+// Moderation records the decision of an employee on a survey waiting for
+// confirmation, every employee decides once per review round.
+type Moderation struct {
+	gorm.Model
+	SurveyRefer   uint          `gorm:"uniqueIndex:idx_moderation"`
+	Round         uint          `gorm:"uniqueIndex:idx_moderation"` // review round of the survey
+	ReviewerRefer uint          `gorm:"uniqueIndex:idx_moderation"` // user id of the employee
+	Senior        bool          // reviewer was a senior employee
+	Decision      ConfirmStatus // confirmed or declined
+	Reason        DeclineReason `json:",omitempty"`
+	Comment       string        `json:",omitempty"`
+}
+
+type DeclineReason string
+
+const (
+	DeclineReasonIncomplete    DeclineReason = "incomplete"
+	DeclineReasonUnclear       DeclineReason = "unclear"
+	DeclineReasonInappropriate DeclineReason = "inappropriate"
+	DeclineReasonDuplicate     DeclineReason = "duplicate"
+	DeclineReasonOther         DeclineReason = "other" // explained in the comment
+)
+
+// Valid reports whether the reason is one of the known decline reasons.
+func (r DeclineReason) Valid() bool {
+	switch r {
+	case DeclineReasonIncomplete, DeclineReasonUnclear, DeclineReasonInappropriate, DeclineReasonDuplicate, DeclineReasonOther:
+		return true
+	}
+
+	return false
+}
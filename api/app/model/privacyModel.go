@@ -0,0 +1,316 @@
+package model
+
+import (
+	"errors"
+	"math"
+
+	"gorm.io/gorm"
+)
+
+// MergedGroup collects the demographic groups that are too small to be disclosed on their own.
+const MergedGroup = "other"
+
+// NoiseMechanism is the differential privacy mechanism used to perturb published counts.
+type NoiseMechanism string
+
+const (
+	NoiseNone     NoiseMechanism = ""
+	NoiseLaplace  NoiseMechanism = "laplace"
+	NoiseGaussian NoiseMechanism = "gaussian" // (epsilon, NoiseDelta) differential privacy
+)
+
+// NoiseDelta is the delta of the gaussian mechanism.
+const NoiseDelta = 1e-5
+
+// Release names the differentially private publications of a survey, each spends
+// the survey epsilon once.
+type Release string
+
+const (
+	ReleaseResults      Release = "results"      // choice and answer counts
+	ReleaseDemographics Release = "demographics" // crosstab and voter details
+)
+
+// PrivacyRelease records a publication of a survey against its privacy budget.
+// The seed makes the noise of repeated queries identical so they spend nothing.
+type PrivacyRelease struct {
+	gorm.Model  `fake:"skip"`
+	SurveyRefer uint    `gorm:"uniqueIndex:idx_privacy_release" fake:"skip"`
+	Release     Release `gorm:"uniqueIndex:idx_privacy_release" fake:"skip"`
+	Epsilon     float64 `fake:"skip"`
+	Seed        int64   `json:"-" fake:"skip"`
+}
+
+// Privacy tells consumers that the published numbers are perturbed.
+type Privacy struct {
+	Noisy     bool
+	Mechanism NoiseMechanism
+	Epsilon   float64
+	Delta     float64 `json:",omitempty"`
+}
+
+// ValidateNoise checks the differential privacy settings chosen by the owner.
+func (s *Survey) ValidateNoise() error {
+	switch s.Noise {
+	case NoiseNone:
+		if s.PrivacyBudget != 0 || s.Epsilon != 0 {
+			return errors.New("privacy budget requires a noise mechanism")
+		}
+		return nil
+	case NoiseLaplace:
+	case NoiseGaussian:
+		if s.Epsilon >= 1 {
+			return errors.New("gaussian noise requires an epsilon below 1")
+		}
+	default:
+		return errors.New("unknown noise mechanism")
+	}
+
+	if !s.Sensitive {
+		return errors.New("noise can only be added to sensitive surveys")
+	}
+	if s.Epsilon <= 0 {
+		return errors.New("epsilon must be positive")
+	}
+	if s.PrivacyBudget < s.Epsilon {
+		return errors.New("privacy budget must cover at least one release")
+	}
+
+	return nil
+}
+
+// AgeBands lists the age groups of the AgeBand dimension.
+var AgeBands = []string{"0-17", "18-24", "25-34", "35-44", "45-54", "55-64", "65+"}
+
+// CrosstabGroups lists every group of a dimension, including groups nobody voted in.
+func CrosstabGroups(dimension CrosstabDimension) []string {
+	switch dimension {
+	case CrosstabDimensionBirthSex:
+		return []string{string(BirthSexMan), string(BirthSexNoRespond), string(BirthSexWoman)}
+	case CrosstabDimensionGenderIdentity:
+		return []string{string(GenderIdentityMan), string(GenderIdentityNonBin), string(GenderIdentityNoRespond), string(GenderIdentityTransgender), string(GenderIdentityWoman)}
+	case CrosstabDimensionAgeBand:
+		return AgeBands
+	case CrosstabDimensionIsResident:
+		return []string{"false", "true"}
+	}
+
+	return []string{"all"}
+}
+
+// DemographicBucket is the number of voters of a choice within a demographic group.
+type DemographicBucket struct {
+	Group  string
+	Voters int
+}
+
+// DemographicTable breaks the voters of a choice down along one dimension.
+type DemographicTable struct {
+	Dimension  CrosstabDimension
+	Suppressed bool // too few voters to disclose any group
+	Buckets    []DemographicBucket
+}
+
+// ChoiceDemographics describes who picked a choice without disclosing individual voters.
+type ChoiceDemographics struct {
+	ChoiceID    uint
+	Voters      int
+	MinCellSize int
+	Privacy     *Privacy `json:",omitempty"`
+	Dimensions  []DemographicTable
+}
+
+// NewChoiceDemographics builds the demographic tables of a choice from the
+// aggregated crosstab rows, groups with less than minCellSize voters are merged.
+func NewChoiceDemographics(choiceID uint, rows []CrosstabRow, minCellSize int) *ChoiceDemographics {
+	demographics := &ChoiceDemographics{ChoiceID: choiceID, MinCellSize: minCellSize, Dimensions: make([]DemographicTable, 0)}
+
+	for _, dimension := range CrosstabDimensions {
+		buckets := make([]DemographicBucket, 0)
+		for _, row := range rows {
+			if row.ChoiceID == choiceID && row.Dimension == dimension {
+				buckets = append(buckets, DemographicBucket{row.Group, row.Count})
+			}
+		}
+
+		if dimension == CrosstabDimensionTotal {
+			// the total is published with the results anyway
+			for _, bucket := range buckets {
+				demographics.Voters += bucket.Voters
+			}
+			continue
+		}
+
+		sizes := make([]int, len(buckets))
+		for i, bucket := range buckets {
+			sizes[i] = bucket.Voters
+		}
+		merged, suppressed := mergeSmallGroups(sizes, minCellSize)
+
+		table := DemographicTable{Dimension: dimension, Suppressed: suppressed, Buckets: make([]DemographicBucket, 0)}
+		if !suppressed {
+			other := DemographicBucket{Group: MergedGroup}
+			for i, bucket := range buckets {
+				if merged[i] {
+					other.Voters += bucket.Voters
+				} else {
+					table.Buckets = append(table.Buckets, bucket)
+				}
+			}
+			if other.Voters > 0 {
+				table.Buckets = append(table.Buckets, other)
+			}
+		}
+
+		demographics.Dimensions = append(demographics.Dimensions, table)
+	}
+
+	return demographics
+}
+
+// Suppress merges the demographic groups of every question that have less than
+// minCellSize respondents and hides the smaller choice counts of the disclosed
+// groups, the totals are left untouched.
+func (c *Crosstab) Suppress(minCellSize int) {
+	c.MinCellSize = minCellSize
+
+	for i := range c.Questions {
+		for j := range c.Questions[i].Dimensions {
+			table := &c.Questions[i].Dimensions[j]
+			if table.Dimension == CrosstabDimensionTotal {
+				continue
+			}
+
+			sizes := make([]int, len(table.Groups))
+			for k, group := range table.Groups {
+				sizes[k] = group.Respondents
+			}
+			merged, suppressed := mergeSmallGroups(sizes, minCellSize)
+			if suppressed {
+				table.Suppressed = true
+				table.Groups = make([]CrosstabGroup, 0)
+				continue
+			}
+
+			groups := make([]CrosstabGroup, 0, len(table.Groups))
+			var other *CrosstabGroup
+			for k, group := range table.Groups {
+				if !merged[k] {
+					groups = append(groups, group)
+					continue
+				}
+
+				if other == nil {
+					other = &CrosstabGroup{Group: MergedGroup, Choices: make([]CrosstabCell, len(group.Choices))}
+					copy(other.Choices, group.Choices)
+				} else {
+					for l := range other.Choices {
+						other.Choices[l].Count += group.Choices[l].Count
+					}
+				}
+				other.Respondents += group.Respondents
+			}
+			if other != nil && other.Respondents > 0 {
+				for l := range other.Choices {
+					other.Choices[l].Percentage = math.Round(float64(other.Choices[l].Count)*10000/float64(other.Respondents)) / 100
+				}
+				groups = append(groups, *other)
+			}
+
+			suppressSmallCells(groups, minCellSize)
+			table.Groups = groups
+		}
+	}
+}
+
+// suppressSmallCells hides the choice counts below k within the groups of a table.
+// The respondents of a group and the totals of a choice are published, so a single
+// hidden cell in a group or in a choice could be derived; the smallest other cell
+// of that group or choice is hidden as well until none is left alone.
+func suppressSmallCells(groups []CrosstabGroup, k int) {
+	type cellIndex struct {
+		group, choice int
+	}
+
+	// every group and every choice of the table
+	lines := make([][]cellIndex, 0)
+	hidden := make([][]bool, len(groups))
+	for g, group := range groups {
+		line := make([]cellIndex, 0, len(group.Choices))
+		hidden[g] = make([]bool, len(group.Choices))
+		for c, cell := range group.Choices {
+			line = append(line, cellIndex{g, c})
+			hidden[g][c] = cell.Count > 0 && cell.Count < k
+		}
+		lines = append(lines, line)
+	}
+	if len(groups) > 0 {
+		for c := range groups[0].Choices {
+			line := make([]cellIndex, 0, len(groups))
+			for g := range groups {
+				line = append(line, cellIndex{g, c})
+			}
+			lines = append(lines, line)
+		}
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for _, line := range lines {
+			count, smallest := 0, -1
+			for i, at := range line {
+				if hidden[at.group][at.choice] {
+					count++
+				} else if smallest == -1 || groups[at.group].Choices[at.choice].Count < groups[line[smallest].group].Choices[line[smallest].choice].Count {
+					smallest = i
+				}
+			}
+			if count == 1 && smallest != -1 {
+				hidden[line[smallest].group][line[smallest].choice] = true
+				changed = true
+			}
+		}
+	}
+
+	for g := range groups {
+		for c := range groups[g].Choices {
+			if hidden[g][c] {
+				cell := &groups[g].Choices[c]
+				cell.Count, cell.Percentage, cell.Suppressed = 0, 0, true
+			}
+		}
+	}
+}
+
+// mergeSmallGroups picks the groups to merge so that no disclosed group, the merged
+// one included, is smaller than k. Small groups are merged first, then the smallest
+// other groups until the merged group is large enough. Otherwise a single small group
+// could be derived from the total. It reports suppressed if even all groups together
+// are too small.
+func mergeSmallGroups(sizes []int, k int) (merged []bool, suppressed bool) {
+	merged = make([]bool, len(sizes))
+	other := 0
+	for i, size := range sizes {
+		if size < k {
+			merged[i] = true
+			other += size
+		}
+	}
+
+	for other > 0 && other < k {
+		smallest := -1
+		for i, size := range sizes {
+			if !merged[i] && (smallest == -1 || size < sizes[smallest]) {
+				smallest = i
+			}
+		}
+		if smallest == -1 {
+			return merged, true
+		}
+
+		merged[smallest] = true
+		other += sizes[smallest]
+	}
+
+	return merged, false
+}
@@ -0,0 +1,28 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergeSmallGroups(t *testing.T) {
+	tests := []struct {
+		name           string
+		sizes          []int
+		wantMerged     []bool
+		wantSuppressed bool
+	}{
+		{"all large", []int{5, 6}, []bool{false, false}, false},
+		{"small groups merged", []int{3, 2, 7}, []bool{true, true, false}, false},
+		{"single small group takes the smallest large one", []int{1, 7, 5}, []bool{true, false, true}, false},
+		{"everything too small", []int{1, 2}, []bool{true, true}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged, suppressed := mergeSmallGroups(tt.sizes, 5)
+			if !reflect.DeepEqual(merged, tt.wantMerged) || suppressed != tt.wantSuppressed {
+				t.Errorf("mergeSmallGroups() = %v, %v, want %v, %v", merged, suppressed, tt.wantMerged, tt.wantSuppressed)
+			}
+		})
+	}
+}
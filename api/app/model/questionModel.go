@@ -1,12 +1,262 @@
 package model
 
 import (
+	"errors"
+	"math"
+	"strings"
+
 	"gorm.io/gorm"
 )
 
 type Question struct {
 	gorm.Model  `fake:"skip"`
-	SurveyRefer uint     `binding:"required" fake:"skip"`
-	Value       string   `binding:"required" fake:"{question}"`
-	Choices     []Choice `gorm:"foreignKey:QuestionRefer" binding:"required" fakesize:"3"`
+	SurveyRefer uint         `binding:"required" fake:"skip"`
+	Value       string       `binding:"required" fake:"{question}"`
+	Type        QuestionType `gorm:"default:single" fake:"skip"`
+	MinChoices  uint         `fake:"skip"` // multi-select lower bound, 0 means at least one
+	MaxChoices  uint         `fake:"skip"` // multi-select upper bound, 0 means every choice
+	MinNumber   *float64     `fake:"skip"` // numeric lower bound
+	MaxNumber   *float64     `fake:"skip"` // numeric upper bound
+	Choices     []Choice     `gorm:"foreignKey:QuestionRefer" fakesize:"3"`
+	Conditions  []Condition  `gorm:"foreignKey:QuestionRefer" fake:"skip"`                   // display conditions, all must hold
+	Votes       []Vote       `gorm:"foreignKey:QuestionRefer" json:",omitempty" fake:"skip"` // numeric and free text answers, only loaded on request
+	Summary     *Summary     `gorm:"-" fake:"skip"`
+}
+
+type QuestionType string
+
+const (
+	QuestionTypeSingle  QuestionType = "single"
+	QuestionTypeMulti   QuestionType = "multi"   // multi-select with MinChoices/MaxChoices
+	QuestionTypeRanking QuestionType = "ranking" // every choice ordered by preference
+	QuestionTypeLikert  QuestionType = "likert"  // choices are the ordered scale points
+	QuestionTypeNumeric QuestionType = "numeric"
+	QuestionTypeText    QuestionType = "text"
+)
+
+// TextAnswerMaxLength limits the size of free text answers.
+const TextAnswerMaxLength = 2000
+
+// Summary holds the type specific aggregation of a question's answers.
+type Summary struct {
+	Count       int
+	Respondents int      // voters that answered the question
+	Mean        *float64 `json:",omitempty"` // likert scale point or numeric value
+	Min         *float64 `json:",omitempty"`
+	Max         *float64 `json:",omitempty"`
+}
+
+// HasChoices reports whether answers of this question are choice indices.
+func (q *Question) HasChoices() bool {
+	switch q.Type {
+	case QuestionTypeNumeric, QuestionTypeText:
+		return false
+	}
+
+	return true
+}
+
+// Validate checks that the question definition is consistent with its type.
+func (q *Question) Validate() error {
+	if strings.TrimSpace(q.Value) == "" {
+		return errors.New("question text can not be empty")
+	}
+
+	switch q.Type {
+	case QuestionTypeSingle, QuestionTypeMulti:
+		if len(q.Choices) < 1 {
+			return errors.New("question must have at least one choice")
+		}
+	case QuestionTypeRanking, QuestionTypeLikert:
+		if len(q.Choices) < 2 {
+			return errors.New("ranking and likert questions must have at least two choices")
+		}
+	case QuestionTypeNumeric, QuestionTypeText:
+		if len(q.Choices) != 0 {
+			return errors.New("numeric and text questions can not have choices")
+		}
+	default:
+		return errors.New("unknown question type")
+	}
+
+	if q.Type == QuestionTypeMulti {
+		if q.MaxChoices != 0 && q.MinChoices > q.MaxChoices {
+			return errors.New("minimum choice count is greater than maximum")
+		}
+		if int(q.MaxChoices) > len(q.Choices) || int(q.MinChoices) > len(q.Choices) {
+			return errors.New("choice limits exceed the number of choices")
+		}
+	} else if q.MinChoices != 0 || q.MaxChoices != 0 {
+		return errors.New("choice limits are only allowed on multi-select questions")
+	}
+
+	if q.Type == QuestionTypeNumeric {
+		if q.MinNumber != nil && q.MaxNumber != nil && *q.MinNumber > *q.MaxNumber {
+			return errors.New("minimum number is greater than maximum")
+		}
+	} else if q.MinNumber != nil || q.MaxNumber != nil {
+		return errors.New("number limits are only allowed on numeric questions")
+	}
+
+	for _, choice := range q.Choices {
+		if strings.TrimSpace(choice.Value) == "" {
+			return errors.New("choice text can not be empty")
+		}
+	}
+
+	return nil
+}
+
+// ValidateAnswer checks a submitted answer against the question type.
+func (q *Question) ValidateAnswer(answer Answer) error {
+	if q.HasChoices() {
+		if answer.Number != nil || answer.Text != nil {
+			return errors.New("choice questions only accept choice indices")
+		}
+
+		seen := make(map[uint]bool)
+		for _, index := range answer.Choices {
+			if len(q.Choices) <= int(index) {
+				return errors.New("vote index out of bounds")
+			}
+			if seen[index] {
+				return errors.New("choice selected more than once")
+			}
+			seen[index] = true
+		}
+	} else if len(answer.Choices) != 0 {
+		return errors.New("numeric and text questions do not accept choices")
+	}
+
+	switch q.Type {
+	case QuestionTypeSingle, QuestionTypeLikert:
+		if len(answer.Choices) != 1 {
+			return errors.New("exactly one choice must be selected")
+		}
+	case QuestionTypeMulti:
+		min := int(q.MinChoices)
+		if min == 0 {
+			min = 1
+		}
+		max := int(q.MaxChoices)
+		if max == 0 {
+			max = len(q.Choices)
+		}
+		if len(answer.Choices) < min || len(answer.Choices) > max {
+			return errors.New("selected choice count is out of bounds")
+		}
+	case QuestionTypeRanking:
+		if len(answer.Choices) != len(q.Choices) {
+			return errors.New("every choice must be ranked")
+		}
+	case QuestionTypeNumeric:
+		if answer.Number == nil || answer.Text != nil {
+			return errors.New("numeric questions require a number")
+		}
+		if q.MinNumber != nil && *answer.Number < *q.MinNumber {
+			return errors.New("number is below the minimum")
+		}
+		if q.MaxNumber != nil && *answer.Number > *q.MaxNumber {
+			return errors.New("number is above the maximum")
+		}
+	case QuestionTypeText:
+		if answer.Text == nil || answer.Number != nil {
+			return errors.New("text questions require a text")
+		}
+		if strings.TrimSpace(*answer.Text) == "" {
+			return errors.New("text answer can not be empty")
+		}
+		if len(*answer.Text) > TextAnswerMaxLength {
+			return errors.New("text answer is too long")
+		}
+	default:
+		return errors.New("unknown question type")
+	}
+
+	return nil
+}
+
+// VotesFor converts a validated answer into the vote rows to be stored.
+// Choice IDs must be loaded on the question.
+func (q *Question) VotesFor(userID uint, answer Answer) (votes []Vote, err error) {
+	if err = q.ValidateAnswer(answer); err != nil {
+		return nil, err
+	}
+
+	votes = make([]Vote, 0)
+
+	if !q.HasChoices() {
+		votes = append(votes, Vote{
+			UserRefer:     &userID,
+			QuestionRefer: q.ID,
+			Number:        answer.Number,
+			Text:          answer.Text,
+		})
+
+		return votes, nil
+	}
+
+	for i, index := range answer.Choices {
+		choiceID := q.Choices[index].ID
+		vote := Vote{
+			UserRefer:     &userID,
+			QuestionRefer: q.ID,
+			ChoiceRefer:   &choiceID,
+		}
+		if q.Type == QuestionTypeRanking {
+			vote.Rank = uint(i + 1)
+		}
+
+		votes = append(votes, vote)
+	}
+
+	return votes, nil
+}
+
+// Summarize derives the summary of the question and the choice percentages from
+// the vote counts. Respondents and the numeric statistics are aggregated by the store.
+func (q *Question) Summarize() {
+	if q.Summary == nil {
+		q.Summary = &Summary{}
+	}
+	summary := q.Summary
+
+	switch q.Type {
+	case QuestionTypeNumeric, QuestionTypeText:
+		return
+	case QuestionTypeLikert:
+		var sum float64
+		summary.Count = 0
+		summary.Mean = nil
+		for i, choice := range q.Choices {
+			sum += float64((i + 1) * choice.VoteCount)
+			summary.Count += choice.VoteCount
+		}
+		if summary.Count > 0 {
+			mean := sum / float64(summary.Count)
+			summary.Mean = &mean
+		}
+	case QuestionTypeRanking:
+		// every respondent ranks every choice
+		summary.Count = 0
+		for _, choice := range q.Choices {
+			if choice.VoteCount > summary.Count {
+				summary.Count = choice.VoteCount
+			}
+		}
+	default:
+		// single and multi-select count the selections
+		summary.Count = 0
+		for _, choice := range q.Choices {
+			summary.Count += choice.VoteCount
+		}
+	}
+
+	for i := range q.Choices {
+		choice := &q.Choices[i]
+		choice.Percentage = 0
+		if summary.Respondents > 0 {
+			choice.Percentage = math.Round(float64(choice.VoteCount)*10000/float64(summary.Respondents)) / 100
+		}
+	}
 }
@@ -0,0 +1,94 @@
+package model
+
+import (
+	"testing"
+)
+
+func TestQuestionValidateAnswer(t *testing.T) {
+	ten := 10.0
+	eleven := 11.0
+	text := "fine"
+	choices := []Choice{{Value: "a"}, {Value: "b"}, {Value: "c"}}
+
+	tests := []struct {
+		name     string
+		question Question
+		answer   Answer
+		wantErr  bool
+	}{
+		{
+			name:     "single",
+			question: Question{Type: QuestionTypeSingle, Choices: choices},
+			answer:   Answer{Choices: []uint{1}},
+		},
+		{
+			name:     "single out of bounds",
+			question: Question{Type: QuestionTypeSingle, Choices: choices},
+			answer:   Answer{Choices: []uint{3}},
+			wantErr:  true,
+		},
+		{
+			name:     "single with two choices",
+			question: Question{Type: QuestionTypeSingle, Choices: choices},
+			answer:   Answer{Choices: []uint{0, 1}},
+			wantErr:  true,
+		},
+		{
+			name:     "multi within limits",
+			question: Question{Type: QuestionTypeMulti, Choices: choices, MinChoices: 2},
+			answer:   Answer{Choices: []uint{0, 2}},
+		},
+		{
+			name:     "multi below minimum",
+			question: Question{Type: QuestionTypeMulti, Choices: choices, MinChoices: 2},
+			answer:   Answer{Choices: []uint{0}},
+			wantErr:  true,
+		},
+		{
+			name:     "multi duplicate choice",
+			question: Question{Type: QuestionTypeMulti, Choices: choices},
+			answer:   Answer{Choices: []uint{1, 1}},
+			wantErr:  true,
+		},
+		{
+			name:     "ranking",
+			question: Question{Type: QuestionTypeRanking, Choices: choices},
+			answer:   Answer{Choices: []uint{2, 0, 1}},
+		},
+		{
+			name:     "ranking incomplete",
+			question: Question{Type: QuestionTypeRanking, Choices: choices},
+			answer:   Answer{Choices: []uint{2, 0}},
+			wantErr:  true,
+		},
+		{
+			name:     "numeric",
+			question: Question{Type: QuestionTypeNumeric, MaxNumber: &ten},
+			answer:   Answer{Number: &ten},
+		},
+		{
+			name:     "numeric above maximum",
+			question: Question{Type: QuestionTypeNumeric, MaxNumber: &ten},
+			answer:   Answer{Number: &eleven},
+			wantErr:  true,
+		},
+		{
+			name:     "text",
+			question: Question{Type: QuestionTypeText},
+			answer:   Answer{Text: &text},
+		},
+		{
+			name:     "text with choices",
+			question: Question{Type: QuestionTypeText},
+			answer:   Answer{Choices: []uint{0}, Text: &text},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.question.ValidateAnswer(tt.answer); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateAnswer() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package model
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Quota caps the respondents of one demographic cell, unset criteria match every
+// respondent. A ballot counts towards every cell its voter falls in and is rejected
+// when one of them is full.
+type Quota struct {
+	gorm.Model     `fake:"skip"`
+	SurveyRefer    uint           `gorm:"index" fake:"skip"`
+	BirthSex       BirthSex       `json:",omitempty" fake:"skip"`
+	GenderIdentity GenderIdentity `json:",omitempty" fake:"skip"`
+	MinAge         *uint          `json:",omitempty" fake:"skip"`
+	MaxAge         *uint          `json:",omitempty" fake:"skip"`
+	IsResident     *bool          `json:",omitempty" fake:"skip"`
+	Target         uint           `fake:"skip"`
+	Filled         uint           `fake:"skip"` // respondents counted by their votes
+}
+
+// QuotaStatus shows the owner how far a survey is from its quotas.
+type QuotaStatus struct {
+	RespondentCap uint // 0 does not cap the respondents
+	Respondents   uint
+	Quotas        []Quota
+	Met           bool       // every quota is met, the survey no longer accepts ballots
+	MetAt         *time.Time `json:",omitempty"`
+}
+
+// Matches reports whether the user falls in the cell of the quota at the given time.
+func (q *Quota) Matches(user *User, at time.Time) bool {
+	if q.BirthSex != "" && q.BirthSex != user.BirthSex {
+		return false
+	}
+	if q.GenderIdentity != "" && q.GenderIdentity != user.GenderIdentity {
+		return false
+	}
+
+	age := Age(user.BirthDate, at)
+	if q.MinAge != nil && age < int(*q.MinAge) {
+		return false
+	}
+	if q.MaxAge != nil && age > int(*q.MaxAge) {
+		return false
+	}
+
+	resident := user.IsResident != nil && *user.IsResident
+	if q.IsResident != nil && *q.IsResident != resident {
+		return false
+	}
+
+	return true
+}
+
+// Met reports whether the quota reached its target.
+func (q *Quota) Met() bool {
+	return q.Filled >= q.Target
+}
+
+// QuotasMet reports whether every quota of the survey is met. Surveys without any
+// quota never meet them.
+func QuotasMet(respondentCap, respondents uint, quotas []Quota) bool {
+	if respondentCap == 0 && len(quotas) == 0 {
+		return false
+	}
+	if respondentCap > 0 && respondents >= respondentCap {
+		return true
+	}
+	if len(quotas) == 0 {
+		return false
+	}
+
+	for i := range quotas {
+		if !quotas[i].Met() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ValidateQuotas rejects quotas no respondent can fill, the fills are reset as
+// they are only counted by votes.
+func (s *Survey) ValidateQuotas() error {
+	for i := range s.Quotas {
+		quota := &s.Quotas[i]
+		if quota.Target == 0 {
+			return errors.New("quota target must be at least 1")
+		}
+		if quota.MinAge != nil && quota.MaxAge != nil && *quota.MinAge > *quota.MaxAge {
+			return errors.New("quota minimum age can not be above its maximum age")
+		}
+		switch quota.BirthSex {
+		case "", BirthSexWoman, BirthSexMan, BirthSexNoRespond:
+		default:
+			return errors.New("quota refers to an unknown birth sex")
+		}
+		switch quota.GenderIdentity {
+		case "", GenderIdentityWoman, GenderIdentityMan, GenderIdentityTransgender, GenderIdentityNonBin, GenderIdentityNoRespond:
+		default:
+			return errors.New("quota refers to an unknown gender identity")
+		}
+
+		quota.Filled = 0
+	}
+
+	return nil
+}
@@ -0,0 +1,77 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQuotaMatches(t *testing.T) {
+	at := time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)
+	eighteen, thirty := uint(18), uint(30)
+	resident := true
+	women := Quota{BirthSex: BirthSexWoman, MinAge: &eighteen, MaxAge: &thirty, Target: 500}
+
+	tests := []struct {
+		name  string
+		quota Quota
+		user  User
+		want  bool
+	}{
+		{"in the cell", women, User{BirthSex: BirthSexWoman, BirthDate: time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)}, true},
+		{"other birth sex", women, User{BirthSex: BirthSexMan, BirthDate: time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)}, false},
+		{"too old", women, User{BirthSex: BirthSexWoman, BirthDate: time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC)}, false},
+		{"no criteria", Quota{Target: 1}, User{}, true},
+		{"residents", Quota{IsResident: &resident, Target: 1}, User{}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.quota.Matches(&tt.user, at); got != tt.want {
+			t.Errorf("%s: Matches() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestQuotasMet(t *testing.T) {
+	tests := []struct {
+		name          string
+		respondentCap uint
+		respondents   uint
+		quotas        []Quota
+		want          bool
+	}{
+		{"no quotas", 0, 10, nil, false},
+		{"cap reached", 10, 10, nil, true},
+		{"cap open", 10, 9, nil, false},
+		{"cells met", 0, 7, []Quota{{Target: 3, Filled: 3}, {Target: 4, Filled: 4}}, true},
+		{"cell open", 0, 7, []Quota{{Target: 3, Filled: 3}, {Target: 5, Filled: 4}}, false},
+		{"cap reached before the cells", 7, 7, []Quota{{Target: 5, Filled: 4}}, true},
+	}
+	for _, tt := range tests {
+		if got := QuotasMet(tt.respondentCap, tt.respondents, tt.quotas); got != tt.want {
+			t.Errorf("%s: QuotasMet() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestValidateQuotas(t *testing.T) {
+	thirty, eighteen := uint(30), uint(18)
+	invalid := []Quota{
+		{Target: 0},
+		{Target: 1, MinAge: &thirty, MaxAge: &eighteen},
+		{Target: 1, BirthSex: "other"},
+		{Target: 1, GenderIdentity: "other"},
+	}
+	for _, quota := range invalid {
+		survey := Survey{Quotas: []Quota{quota}}
+		if err := survey.ValidateQuotas(); err == nil {
+			t.Errorf("ValidateQuotas(%+v) succeeded", quota)
+		}
+	}
+
+	survey := Survey{Quotas: []Quota{{Target: 5, Filled: 3}}}
+	if err := survey.ValidateQuotas(); err != nil {
+		t.Fatalf("ValidateQuotas() error = %v", err)
+	}
+	if survey.Quotas[0].Filled != 0 {
+		t.Errorf("ValidateQuotas() kept the fill %d", survey.Quotas[0].Filled)
+	}
+}
@@ -0,0 +1,255 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// SeriesRequest links a survey to the earlier wave of the series it repeats.
+type SeriesRequest struct {
+	PreviousWave uint `binding:"required"`
+}
+
+// SeriesWave is one survey of a series, from the first wave to the latest.
+type SeriesWave struct {
+	SurveyID    uint
+	Subject     string
+	DateStart   time.Time
+	DateEnd     time.Time
+	MinCellSize int      // smaller demographic groups are merged
+	Privacy     *Privacy `json:",omitempty"`
+	Unfinished  bool     `json:",omitempty"` // voting has not ended, the wave has no results yet
+}
+
+// SeriesResults lines up the choice questions of the waves of a series and reports
+// the distribution of every wave within the groups of a demographic dimension.
+// Ranking questions count first preferences, numeric and text questions are left out
+// as in the crosstab.
+type SeriesResults struct {
+	SurveyID  uint // latest wave
+	Dimension CrosstabDimension
+	Group     string `json:",omitempty"` // only this group is reported
+	Waves     []SeriesWave
+	Questions []SeriesQuestion
+}
+
+// SeriesQuestion is a question matched across the waves by its text and type.
+type SeriesQuestion struct {
+	Value       string
+	Type        QuestionType
+	QuestionIDs []uint // one per wave, 0 if the wave did not ask the question
+	Groups      []SeriesGroup
+}
+
+type SeriesGroup struct {
+	Group string
+	Waves []SeriesDistribution // one per wave
+}
+
+// SeriesDistribution is the choice distribution of a group in one wave.
+type SeriesDistribution struct {
+	SurveyID    uint
+	Asked       bool // the wave asked the question
+	Suppressed  bool // the group was too small to be disclosed in this wave
+	Respondents int
+	Choices     []SeriesCell
+}
+
+// SeriesCell is a choice matched across the waves by its text.
+type SeriesCell struct {
+	ChoiceID   uint // 0 if the wave did not offer the choice
+	Value      string
+	Count      int
+	Percentage float64
+	Suppressed bool     `json:",omitempty"` // too few votes to disclose in this wave
+	Change     *float64 `json:",omitempty"` // percentage points since the previous wave
+}
+
+// ValidateSeriesFilter checks the demographic breakdown asked for a series, a group
+// can only be picked within a dimension.
+func ValidateSeriesFilter(dimension CrosstabDimension, group string) error {
+	for _, known := range CrosstabDimensions {
+		if dimension == known {
+			if dimension == CrosstabDimensionTotal && group != "" {
+				return errors.New("a group needs a dimension")
+			}
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unknown dimension %q", dimension)
+}
+
+// NewSeriesResults matches the questions and choices of the suppressed crosstabs of
+// the waves, ordered from the first wave, and computes the change of every choice
+// share between consecutive waves. Questions are matched by text and type, repeated
+// texts by their occurrence, choices by text. Unfinished waves have a nil crosstab.
+func NewSeriesResults(waves []SeriesWave, crosstabs []*Crosstab, dimension CrosstabDimension, group string) *SeriesResults {
+	series := &SeriesResults{Dimension: dimension, Group: group, Waves: waves, Questions: make([]SeriesQuestion, 0)}
+	if len(waves) > 0 {
+		series.SurveyID = waves[len(waves)-1].SurveyID
+	}
+
+	type questionKey struct {
+		value      string
+		typ        QuestionType
+		occurrence int
+	}
+
+	surveyIDs := make([]uint, len(waves))
+	for w, wave := range waves {
+		surveyIDs[w] = wave.SurveyID
+	}
+
+	// matched questions of every wave
+	index := make(map[questionKey]int)
+	matched := make([][]*CrosstabQuestion, 0)
+	for w, crosstab := range crosstabs {
+		if crosstab == nil {
+			continue
+		}
+		occurrences := make(map[questionKey]int)
+		for q := range crosstab.Questions {
+			question := &crosstab.Questions[q]
+			key := questionKey{value: seriesText(question.Value), typ: question.Type}
+			key.occurrence = occurrences[key]
+			occurrences[key]++
+
+			i, ok := index[key]
+			if !ok {
+				i = len(matched)
+				index[key] = i
+				matched = append(matched, make([]*CrosstabQuestion, len(crosstabs)))
+				series.Questions = append(series.Questions, SeriesQuestion{Value: question.Value, Type: question.Type, QuestionIDs: make([]uint, len(crosstabs))})
+			}
+			matched[i][w] = question
+			series.Questions[i].QuestionIDs[w] = question.QuestionID
+		}
+	}
+
+	for i := range series.Questions {
+		series.Questions[i].Groups = seriesGroups(surveyIDs, matched[i], dimension, group)
+	}
+
+	return series
+}
+
+// seriesGroups reports the distributions of the groups of a question across the waves
+func seriesGroups(surveyIDs []uint, waves []*CrosstabQuestion, dimension CrosstabDimension, group string) []SeriesGroup {
+	// choices in the order they first appear
+	choices := make([]string, 0)
+	values := make(map[string]string)
+	groups := make([]string, 0)
+	if group != "" {
+		groups = append(groups, group)
+	}
+	known := map[string]bool{group: true}
+	for _, question := range waves {
+		if question == nil {
+			continue
+		}
+		for _, table := range question.Dimensions {
+			if table.Dimension != dimension {
+				continue
+			}
+			for _, crosstabGroup := range table.Groups {
+				if !known[crosstabGroup.Group] && group == "" {
+					known[crosstabGroup.Group] = true
+					groups = append(groups, crosstabGroup.Group)
+				}
+			}
+		}
+		for _, cell := range seriesCells(question) {
+			key := seriesText(cell.Value)
+			if _, ok := values[key]; !ok {
+				values[key] = cell.Value
+				choices = append(choices, key)
+			}
+		}
+	}
+
+	seriesGroups := make([]SeriesGroup, 0, len(groups))
+	for _, name := range groups {
+		seriesGroup := SeriesGroup{Group: name, Waves: make([]SeriesDistribution, len(waves))}
+		for w, question := range waves {
+			seriesGroup.Waves[w] = seriesDistribution(question, dimension, name, choices, values)
+			seriesGroup.Waves[w].SurveyID = surveyIDs[w]
+		}
+
+		for w := 1; w < len(seriesGroup.Waves); w++ {
+			previous, current := &seriesGroup.Waves[w-1], &seriesGroup.Waves[w]
+			if len(previous.Choices) != len(current.Choices) || previous.Suppressed || current.Suppressed {
+				continue
+			}
+			for c := range current.Choices {
+				if previous.Choices[c].ChoiceID == 0 || current.Choices[c].ChoiceID == 0 || previous.Choices[c].Suppressed || current.Choices[c].Suppressed {
+					continue
+				}
+				change := math.Round((current.Choices[c].Percentage-previous.Choices[c].Percentage)*100) / 100
+				current.Choices[c].Change = &change
+			}
+		}
+
+		seriesGroups = append(seriesGroups, seriesGroup)
+	}
+
+	return seriesGroups
+}
+
+// seriesDistribution picks the group of one wave, a group missing from an asked
+// question was merged or suppressed
+func seriesDistribution(question *CrosstabQuestion, dimension CrosstabDimension, group string, choices []string, values map[string]string) SeriesDistribution {
+	distribution := SeriesDistribution{Choices: make([]SeriesCell, 0)}
+	if question == nil {
+		return distribution
+	}
+	distribution.Asked = true
+
+	var found *CrosstabGroup
+	for _, table := range question.Dimensions {
+		if table.Dimension != dimension {
+			continue
+		}
+		for g := range table.Groups {
+			if table.Groups[g].Group == group {
+				found = &table.Groups[g]
+			}
+		}
+	}
+	if found == nil {
+		// the total is never suppressed, nobody answered
+		distribution.Suppressed = dimension != CrosstabDimensionTotal
+		return distribution
+	}
+	distribution.Respondents = found.Respondents
+
+	cells := make(map[string]CrosstabCell)
+	for _, cell := range found.Choices {
+		cells[seriesText(cell.Value)] = cell
+	}
+	for _, choice := range choices {
+		cell := cells[choice]
+		distribution.Choices = append(distribution.Choices, SeriesCell{ChoiceID: cell.ChoiceID, Value: values[choice], Count: cell.Count, Percentage: cell.Percentage, Suppressed: cell.Suppressed})
+	}
+
+	return distribution
+}
+
+// seriesCells returns the choices of a question, every group lists all of them
+func seriesCells(question *CrosstabQuestion) []CrosstabCell {
+	for _, table := range question.Dimensions {
+		for _, group := range table.Groups {
+			return group.Choices
+		}
+	}
+
+	return nil
+}
+
+// seriesText normalizes question and choice texts for matching
+func seriesText(value string) string {
+	return strings.ToLower(strings.Join(strings.Fields(value), " "))
+}
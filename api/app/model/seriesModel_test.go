@@ -0,0 +1,131 @@
+package model
+
+import (
+	"testing"
+)
+
+// seriesCrosstab is a wave with one likert question answered by residents and others
+func seriesCrosstab(surveyID, questionID uint, value string, resident, other []int) *Crosstab {
+	survey := &Survey{Questions: []Question{{Value: value, Type: QuestionTypeLikert, Choices: []Choice{{Value: "bad"}, {Value: "good"}}}}}
+	survey.ID = surveyID
+	survey.Questions[0].ID = questionID
+	survey.Questions[0].Choices[0].ID, survey.Questions[0].Choices[1].ID = questionID*10, questionID*10+1
+
+	rows := make([]CrosstabRow, 0)
+	for _, group := range []struct {
+		dimension CrosstabDimension
+		name      string
+		counts    []int
+	}{
+		{CrosstabDimensionTotal, "all", []int{resident[0] + other[0], resident[1] + other[1]}},
+		{CrosstabDimensionIsResident, "false", other},
+		{CrosstabDimensionIsResident, "true", resident},
+	} {
+		respondents := group.counts[0] + group.counts[1]
+		for i, count := range group.counts {
+			rows = append(rows, CrosstabRow{QuestionID: questionID, ChoiceID: survey.Questions[0].Choices[i].ID, Dimension: group.dimension, Group: group.name,
+				Count: count, Respondents: respondents, Percentage: float64(count*100) / float64(respondents)})
+		}
+	}
+
+	crosstab := NewCrosstab(survey, rows)
+	crosstab.Suppress(5)
+	return crosstab
+}
+
+func TestNewSeriesResults(t *testing.T) {
+	crosstabs := []*Crosstab{
+		seriesCrosstab(1, 1, "How satisfied are you?", []int{5, 5}, []int{5, 5}),
+		seriesCrosstab(2, 7, " how satisfied  are you?", []int{5, 20}, []int{5, 5}),
+	}
+	waves := []SeriesWave{{SurveyID: 1}, {SurveyID: 2}}
+
+	series := NewSeriesResults(waves, crosstabs, CrosstabDimensionIsResident, "true")
+	if series.SurveyID != 2 || len(series.Questions) != 1 {
+		t.Fatalf("NewSeriesResults() = %+v, want one matched question", series)
+	}
+
+	question := series.Questions[0]
+	if question.QuestionIDs[0] != 1 || question.QuestionIDs[1] != 7 || len(question.Groups) != 1 {
+		t.Fatalf("question = %+v", question)
+	}
+
+	latest := question.Groups[0].Waves[1]
+	if latest.SurveyID != 2 || latest.Respondents != 25 || latest.Choices[1].ChoiceID != 71 {
+		t.Fatalf("latest wave = %+v", latest)
+	}
+	if change := latest.Choices[1].Change; change == nil || *change != 30 {
+		t.Errorf("change of good = %v, want 30", change)
+	}
+	if question.Groups[0].Waves[0].Choices[1].Change != nil {
+		t.Error("the first wave has a change")
+	}
+
+	// a hidden count has no change
+	crosstabs[1] = seriesCrosstab(2, 7, "How satisfied are you?", []int{2, 20}, []int{5, 5})
+	series = NewSeriesResults(waves, crosstabs, CrosstabDimensionIsResident, "true")
+	if cell := series.Questions[0].Groups[0].Waves[1].Choices[0]; !cell.Suppressed || cell.Change != nil {
+		t.Errorf("small cell = %+v, want it suppressed without a change", cell)
+	}
+
+	// too few other voters in the second wave, both groups are merged there
+	crosstabs[1] = seriesCrosstab(2, 7, "How satisfied are you?", []int{2, 8}, []int{1, 1})
+	series = NewSeriesResults(waves, crosstabs, CrosstabDimensionIsResident, "")
+	groups := series.Questions[0].Groups
+	if len(groups) != 3 || groups[0].Group != "false" || groups[2].Group != MergedGroup {
+		t.Fatalf("groups = %+v", groups)
+	}
+	if second := groups[1].Waves[1]; !second.Suppressed || len(second.Choices) != 0 {
+		t.Errorf("small group disclosed: %+v", second)
+	}
+	if first := groups[2].Waves[0]; !first.Suppressed || groups[2].Waves[1].Respondents != 12 {
+		t.Errorf("merged group = %+v", groups[2])
+	}
+}
+
+func TestNewSeriesResultsUnfinished(t *testing.T) {
+	crosstabs := []*Crosstab{seriesCrosstab(1, 1, "Commute", []int{5, 5}, []int{5, 5}), nil}
+	waves := []SeriesWave{{SurveyID: 1}, {SurveyID: 2, Unfinished: true}}
+
+	series := NewSeriesResults(waves, crosstabs, CrosstabDimensionTotal, "")
+	if series.SurveyID != 2 || len(series.Questions) != 1 {
+		t.Fatalf("NewSeriesResults() = %+v, want the question of the finished wave", series)
+	}
+
+	running := series.Questions[0].Groups[0].Waves[1]
+	if running.SurveyID != 2 || running.Asked || len(running.Choices) != 0 {
+		t.Errorf("running wave = %+v, want it without results", running)
+	}
+}
+
+func TestNewSeriesResultsUnmatched(t *testing.T) {
+	crosstabs := []*Crosstab{
+		seriesCrosstab(1, 1, "Commute", []int{5, 5}, []int{5, 5}),
+		seriesCrosstab(2, 2, "Canteen", []int{5, 5}, []int{5, 5}),
+	}
+
+	series := NewSeriesResults([]SeriesWave{{SurveyID: 1}, {SurveyID: 2}}, crosstabs, CrosstabDimensionTotal, "")
+	if len(series.Questions) != 2 {
+		t.Fatalf("questions = %+v, want 2", series.Questions)
+	}
+
+	canteen := series.Questions[1].Groups[0]
+	if canteen.Waves[0].Asked || canteen.Waves[0].Suppressed || !canteen.Waves[1].Asked {
+		t.Errorf("canteen waves = %+v", canteen.Waves)
+	}
+	if canteen.Waves[1].Choices[0].Change != nil {
+		t.Error("change of a question the previous wave did not ask")
+	}
+}
+
+func TestValidateSeriesFilter(t *testing.T) {
+	if err := ValidateSeriesFilter(CrosstabDimensionAgeBand, "18-24"); err != nil {
+		t.Errorf("ValidateSeriesFilter() error = %v", err)
+	}
+	if err := ValidateSeriesFilter(CrosstabDimensionTotal, "true"); err == nil {
+		t.Error("group without a dimension accepted")
+	}
+	if err := ValidateSeriesFilter("Residency", ""); err == nil {
+		t.Error("unknown dimension accepted")
+	}
+}
@@ -1,6 +1,7 @@
 package model
 
 import (
+	"errors"
 	"time"
 
 	"gorm.io/gorm"
@@ -8,19 +9,255 @@ import (
 
 type Survey struct {
 	gorm.Model    `fake:"skip"`
-	UserRefer     uint          `fake:"skip"`
-	Questions     []Question    `gorm:"foreignKey:SurveyRefer" binding:"required" valid:"required~You must add at least one question" fakesize:"5"`
-	Subject       string        `binding:"required" fake:"{sentence:3}"`
-	Description   string        `binding:"required" fake:"{paragraph:2,3,4,aa}"`
-	DateStart     time.Time     `binding:"required" fake:"{daterange:2022-01-01,2022-03-25}" format:"yyyy-MM-dd"`
-	DateEnd       time.Time     `binding:"required" fake:"{daterange:2022-02-25,2022-04-25}" format:"yyyy-MM-dd"`
-	ConfirmStatus ConfirmStatus `gorm:"default:waiting" fake:"{randomstring:[waiting,declined,confirmed]}"`
+	UserRefer     uint           `fake:"skip"`
+	Questions     []Question     `gorm:"foreignKey:SurveyRefer" binding:"required" valid:"required~You must add at least one question" fakesize:"5"`
+	Subject       string         `binding:"required" fake:"{sentence:3}"`
+	Description   string         `binding:"required" fake:"{paragraph:2,3,4,aa}"`
+	DateStart     time.Time      `binding:"required" fake:"{daterange:2022-01-01,2022-03-25}" format:"yyyy-MM-dd"`
+	DateEnd       time.Time      `binding:"required" fake:"{daterange:2022-02-25,2022-04-25}" format:"yyyy-MM-dd"`
+	ConfirmStatus ConfirmStatus  `gorm:"default:draft" fake:"{randomstring:[waiting,declined,confirmed]}"`
+	Sensitive     bool           `fake:"skip"`                   // sensitive topics need a senior reviewer, flagged by reviewers or implied by noise
+	Audience      uint           `fake:"skip"`                   // expected number of respondents, estimated from the eligibility for the review
+	ReviewRound   uint           `fake:"skip"`                   // incremented every time the survey is submitted for review
+	SubmittedAt   *time.Time     `json:",omitempty" fake:"skip"` // start of the current review round
+	ClaimedBy     *uint          `json:",omitempty" fake:"skip"` // employee reviewing the survey
+	ClaimedUntil  *time.Time     `json:",omitempty" fake:"skip"` // the claim is released after this time
+	MinCellSize   uint           `fake:"skip"`                   // voters a demographic group needs to be disclosed, raises the deployment default
+	Noise         NoiseMechanism `fake:"skip"`                   // differential privacy for published counts, empty disables
+	PrivacyBudget float64        `fake:"skip"`                   // total epsilon all releases of the results may spend
+	Epsilon       float64        `fake:"skip"`                   // epsilon spent by each release
+	BallotChanges bool           `fake:"skip"`                   // voters may replace their answers while the survey is active
+	Anonymous     bool           `fake:"skip"`                   // votes are stored without their voter
+	Private       bool           `fake:"skip"`                   // voting needs an invitation, hidden from the public lists
+	BallotRoot    string         `json:"-" fake:"skip"`          // root of the ballot log, published with the log once voting closed
+	Eligibility   Eligibility    `gorm:"embedded" fake:"skip"`   // who may vote, everyone registered by default
+	RespondentCap uint           `fake:"skip"`                   // global quota, 0 does not cap the respondents
+	Respondents   uint           `fake:"skip"`                   // ballots counted towards the quotas, changed ballots count once
+	QuotasMetAt   *time.Time     `json:",omitempty" fake:"skip"` // voting closed early as every quota was met
+	PreviousWave  *uint          `json:",omitempty" fake:"skip"` // earlier survey of the series this one repeats
+	Quotas        []Quota        `gorm:"foreignKey:SurveyRefer" json:",omitempty" fake:"skip"`
+	Moderations   []Moderation   `gorm:"foreignKey:SurveyRefer" json:",omitempty" fake:"skip"`
+	Review        *Review        `gorm:"-" json:",omitempty" fake:"skip"`
+	Privacy       *Privacy       `gorm:"-" json:",omitempty" fake:"skip"`
+}
+
+// PublicSurvey is what anyone may see of a survey, the review state, the counters
+// and the invitees are left out.
+type PublicSurvey struct {
+	ID            uint
+	Subject       string
+	Description   string
+	DateStart     time.Time
+	DateEnd       time.Time
+	Questions     []Question
+	Eligibility   Eligibility
+	BallotChanges bool
+	Anonymous     bool
+	Private       bool
+	Noise         NoiseMechanism `json:",omitempty"`
+	Privacy       *Privacy       `json:",omitempty"`
+}
+
+// NewPublicSurvey copies the public fields of a survey.
+func NewPublicSurvey(survey *Survey) *PublicSurvey {
+	eligibility := survey.Eligibility
+	eligibility.Invitees = nil
+
+	return &PublicSurvey{
+		ID:            survey.ID,
+		Subject:       survey.Subject,
+		Description:   survey.Description,
+		DateStart:     survey.DateStart,
+		DateEnd:       survey.DateEnd,
+		Questions:     survey.Questions,
+		Eligibility:   eligibility,
+		BallotChanges: survey.BallotChanges,
+		Anonymous:     survey.Anonymous,
+		Private:       survey.Private,
+		Noise:         survey.Noise,
+		Privacy:       survey.Privacy,
+	}
+}
+
+// Review is the approval progress of a survey waiting for confirmation.
+type Review struct {
+	Approvals         int
+	RequiredApprovals int
+	SeniorRequired    bool
+	SeniorApproved    bool
+	WaitingHours      int  // hours since the survey was submitted
+	Overdue           bool // waiting longer than the review SLA
+	EligibleUsers     int  // registered users the eligibility criteria admit
+}
+
+// ReviewQueue summarizes the surveys waiting for confirmation.
+type ReviewQueue struct {
+	Waiting           int
+	Claimed           int
+	Overdue           int
+	SLAHours          int
+	OldestSubmittedAt *time.Time `json:",omitempty"`
+}
+
+// EstimateAudience is the expected number of respondents, the registered users the
+// eligibility criteria admit capped by the respondent cap.
+func EstimateAudience(survey *Survey, eligibleUsers int) uint {
+	audience := uint(eligibleUsers)
+	if survey.RespondentCap > 0 && survey.RespondentCap < audience {
+		audience = survey.RespondentCap
+	}
+
+	return audience
+}
+
+// Claimed reports whether another reviewer holds an unexpired claim on the survey.
+func (s *Survey) Claimed(reviewerID uint, now time.Time) bool {
+	return s.ClaimedBy != nil && *s.ClaimedBy != reviewerID && s.ClaimedUntil != nil && s.ClaimedUntil.After(now)
 }
 
 type ConfirmStatus string
 
 const (
+	ConfirmStatusDraft    ConfirmStatus = "draft" // editable by the owner, not submitted for review
 	ConfirmStatusWaiting  ConfirmStatus = "waiting"
 	ConfirmStatusDeclined ConfirmStatus = "declined"
 	ConfirmStatusAccepted ConfirmStatus = "confirmed"
+	ConfirmStatusArchived ConfirmStatus = "archived" // closed survey taken out of the result lists
+)
+
+// SurveyStatus is the lifecycle state of a survey, confirmed surveys are
+// scheduled, active or closed depending on DateStart and DateEnd.
+type SurveyStatus string
+
+const (
+	SurveyStatusDraft     SurveyStatus = "draft"
+	SurveyStatusWaiting   SurveyStatus = "waiting"
+	SurveyStatusDeclined  SurveyStatus = "declined"
+	SurveyStatusScheduled SurveyStatus = "scheduled"
+	SurveyStatusActive    SurveyStatus = "active"
+	SurveyStatusClosed    SurveyStatus = "closed"
+	SurveyStatusArchived  SurveyStatus = "archived"
 )
+
+// Status derives the lifecycle state of the survey at the given time.
+func (s *Survey) Status(now time.Time) SurveyStatus {
+	switch s.ConfirmStatus {
+	case ConfirmStatusAccepted:
+		if now.Before(s.DateStart) {
+			return SurveyStatusScheduled
+		}
+		if now.After(s.DateEnd) {
+			return SurveyStatusClosed
+		}
+		return SurveyStatusActive
+	case ConfirmStatusWaiting:
+		return SurveyStatusWaiting
+	case ConfirmStatusDeclined:
+		return SurveyStatusDeclined
+	case ConfirmStatusArchived:
+		return SurveyStatusArchived
+	}
+
+	return SurveyStatusDraft
+}
+
+// Validate checks the survey questions before it is persisted.
+func (s *Survey) Validate() error {
+	if len(s.Questions) == 0 {
+		return errors.New("you must add at least one question")
+	}
+	if !s.DateStart.Before(s.DateEnd) {
+		return errors.New("survey must start before it ends")
+	}
+
+	// dates are compared as text by the store, keep them in one zone
+	s.DateStart = s.DateStart.UTC()
+	s.DateEnd = s.DateEnd.UTC()
+
+	for i := range s.Questions {
+		if s.Questions[i].Type == "" {
+			s.Questions[i].Type = QuestionTypeSingle
+		}
+		if err := s.Questions[i].Validate(); err != nil {
+			return err
+		}
+	}
+
+	if err := s.ValidateNoise(); err != nil {
+		return err
+	}
+	if err := s.ValidateAnonymity(); err != nil {
+		return err
+	}
+	if err := s.Eligibility.Validate(); err != nil {
+		return err
+	}
+	if err := s.ValidateQuotas(); err != nil {
+		return err
+	}
+
+	return s.ValidateConditions()
+}
+
+// ValidateUpdate checks the dates of a partial update against the stored survey and
+// keeps them in UTC as Validate does, zero dates are left unchanged.
+func (s *Survey) ValidateUpdate(stored *Survey) error {
+	start, end := stored.DateStart, stored.DateEnd
+	if !s.DateStart.IsZero() {
+		s.DateStart = s.DateStart.UTC()
+		start = s.DateStart
+	}
+	if !s.DateEnd.IsZero() {
+		s.DateEnd = s.DateEnd.UTC()
+		end = s.DateEnd
+	}
+
+	if !start.Before(end) {
+		return errors.New("survey must start before it ends")
+	}
+
+	return nil
+}
+
+// ResetManaged clears the fields only the server writes, so that an owner creating
+// or replacing a survey can not forge its review, its quota counters, its ballot root
+// or votes that bypass the ballot.
+func (s *Survey) ResetManaged() {
+	// owners can not opt out of a senior review, a noise mechanism implies a sensitive topic
+	s.Sensitive = s.Noise != NoiseNone
+	s.Audience = 0
+
+	s.ReviewRound = 0
+	s.SubmittedAt = nil
+	s.ClaimedBy = nil
+	s.ClaimedUntil = nil
+	s.Moderations = nil
+	s.Review = nil
+
+	s.Respondents = 0
+	s.QuotasMetAt = nil
+	for i := range s.Quotas {
+		s.Quotas[i].Filled = 0
+	}
+
+	// a root set in advance would never be replaced by the root of the log
+	s.BallotRoot = ""
+
+	for i := range s.Questions {
+		s.Questions[i].Votes = nil
+		for j := range s.Questions[i].Choices {
+			s.Questions[i].Choices[j].Votes = nil
+		}
+	}
+}
+
+// Question returns the loaded question with the given id, or nil.
+func (s *Survey) Question(id uint) *Question {
+	for i := range s.Questions {
+		if s.Questions[i].ID == id {
+			return &s.Questions[i]
+		}
+	}
+
+	return nil
+}
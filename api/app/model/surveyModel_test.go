@@ -0,0 +1,95 @@
+package model
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSurveyResetManaged(t *testing.T) {
+	now := time.Now()
+	reviewer := uint(3)
+	survey := Survey{
+		Subject:      "s",
+		Sensitive:    true,
+		Audience:     10,
+		ReviewRound:  2,
+		SubmittedAt:  &now,
+		ClaimedBy:    &reviewer,
+		ClaimedUntil: &now,
+		Moderations:  []Moderation{{Round: 1, Senior: true, Decision: ConfirmStatusAccepted}},
+		Respondents:  99,
+		QuotasMetAt:  &now,
+		Quotas:       []Quota{{Target: 5, Filled: 5}},
+		BallotRoot:   "bogus",
+		Questions:    []Question{{Value: "q", Votes: []Vote{{}}, Choices: []Choice{{Value: "a", Votes: []Vote{{}}}}}},
+	}
+
+	survey.ResetManaged()
+	if survey.Sensitive || survey.Audience != 0 {
+		t.Errorf("ResetManaged() kept the owner's senior review settings: %+v", survey)
+	}
+	survey.Noise = NoiseLaplace
+	if survey.ResetManaged(); !survey.Sensitive {
+		t.Error("ResetManaged() cleared the sensitivity of a noisy survey")
+	}
+	if survey.ReviewRound != 0 || survey.SubmittedAt != nil || survey.ClaimedBy != nil || survey.ClaimedUntil != nil || survey.Moderations != nil {
+		t.Errorf("ResetManaged() kept the review: %+v", survey)
+	}
+	if survey.Respondents != 0 || survey.QuotasMetAt != nil || survey.Quotas[0].Filled != 0 {
+		t.Errorf("ResetManaged() kept the quota counters: %+v", survey)
+	}
+	if survey.BallotRoot != "" {
+		t.Errorf("ResetManaged() kept the ballot root %q", survey.BallotRoot)
+	}
+	if survey.Questions[0].Votes != nil || survey.Questions[0].Choices[0].Votes != nil {
+		t.Errorf("ResetManaged() kept the votes: %+v", survey.Questions)
+	}
+	if survey.Subject != "s" || survey.Quotas[0].Target != 5 {
+		t.Errorf("ResetManaged() cleared the fields of the owner: %+v", survey)
+	}
+}
+
+func TestNewPublicSurvey(t *testing.T) {
+	reviewer, minAge := uint(3), uint(18)
+	survey := &Survey{Subject: "s", ClaimedBy: &reviewer, ReviewRound: 2, Respondents: 7,
+		Eligibility: Eligibility{MinAge: &minAge, Invitees: []string{"12345678901"}}}
+
+	public := NewPublicSurvey(survey)
+	data, err := json.Marshal(public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range []string{"Invitees", "12345678901", "ClaimedBy", "ReviewRound", "Respondents", "UserRefer"} {
+		if strings.Contains(string(data), field) {
+			t.Errorf("public survey discloses %s: %s", field, data)
+		}
+	}
+	if public.Eligibility.MinAge == nil || len(survey.Eligibility.Invitees) != 1 {
+		t.Errorf("NewPublicSurvey() eligibility = %+v, survey invitees = %v", public.Eligibility, survey.Eligibility.Invitees)
+	}
+}
+
+func TestSurveyValidateUpdate(t *testing.T) {
+	kyiv := time.FixedZone("EET", 2*60*60)
+	stored := &Survey{DateStart: time.Date(2022, 5, 1, 0, 0, 0, 0, time.UTC), DateEnd: time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)}
+
+	update := Survey{DateStart: time.Date(2022, 5, 10, 0, 0, 0, 0, kyiv)}
+	if err := update.ValidateUpdate(stored); err != nil {
+		t.Fatalf("ValidateUpdate() error = %v", err)
+	}
+	if update.DateStart.Location() != time.UTC || !update.DateEnd.IsZero() {
+		t.Errorf("ValidateUpdate() dates = %v - %v, want the start in UTC and no end", update.DateStart, update.DateEnd)
+	}
+
+	for _, update := range []Survey{
+		{DateStart: stored.DateEnd},
+		{DateEnd: time.Date(2022, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{DateStart: time.Date(2022, 7, 1, 0, 0, 0, 0, time.UTC), DateEnd: time.Date(2022, 6, 15, 0, 0, 0, 0, time.UTC)},
+	} {
+		if err := update.ValidateUpdate(stored); err == nil {
+			t.Errorf("ValidateUpdate(%v - %v) succeeded", update.DateStart, update.DateEnd)
+		}
+	}
+}
@@ -0,0 +1,14 @@
+package model
+
+import (
+	"gorm.io/gorm"
+)
+
+// SurveyTransition records a status change of a survey, CreatedAt is the time of the change.
+type SurveyTransition struct {
+	gorm.Model
+	SurveyRefer uint `gorm:"index"`
+	ActorRefer  uint // user that made the change
+	From        SurveyStatus
+	To          SurveyStatus
+}
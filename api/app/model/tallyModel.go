@@ -0,0 +1,129 @@
+package model
+
+import "math"
+
+// Tally is the materialized vote count of a choice, or with ChoiceRefer 0 of the
+// question itself. Tallies are updated along with the votes they count, results
+// are read from them instead of the votes.
+type Tally struct {
+	QuestionRefer uint     `gorm:"primaryKey;autoIncrement:false"`
+	ChoiceRefer   uint     `gorm:"primaryKey;autoIncrement:false"` // 0 for the question tally
+	Count         int      // votes of the choice, answers of a numeric or text question
+	Respondents   int      // question tally only
+	RankSum       int      // ranking choices, for the mean rank
+	NumberSum     float64  // numeric questions
+	NumberMin     *float64 `json:",omitempty"`
+	NumberMax     *float64 `json:",omitempty"`
+}
+
+// TallyDrift reports a stored tally that does not match the votes it counts.
+type TallyDrift struct {
+	Stored  Tally
+	Counted Tally
+}
+
+type tallyKey struct {
+	questionID uint
+	choiceID   uint
+}
+
+// NewTallies counts the votes of a single ballot into the tallies they add to.
+func NewTallies(votes []Vote) []Tally {
+	tallies := make([]Tally, 0)
+	index := make(map[tallyKey]int)
+
+	tally := func(questionID, choiceID uint) *Tally {
+		key := tallyKey{questionID, choiceID}
+		if i, ok := index[key]; ok {
+			return &tallies[i]
+		}
+
+		index[key] = len(tallies)
+		tallies = append(tallies, Tally{QuestionRefer: questionID, ChoiceRefer: choiceID})
+		if choiceID == 0 {
+			// a ballot answers a question once
+			tallies[len(tallies)-1].Respondents = 1
+		}
+
+		return &tallies[len(tallies)-1]
+	}
+
+	for _, vote := range votes {
+		question := tally(vote.QuestionRefer, 0)
+
+		if vote.ChoiceRefer != nil {
+			choice := tally(vote.QuestionRefer, *vote.ChoiceRefer)
+			choice.Count++
+			choice.RankSum += int(vote.Rank)
+			continue
+		}
+
+		question.Count++
+		if vote.Number != nil {
+			n := *vote.Number
+			question.NumberSum += n
+			question.NumberMin = &n
+			question.NumberMax = &n
+		}
+	}
+
+	return tallies
+}
+
+// Equal reports whether two tallies agree, number sums may differ by rounding.
+func (t Tally) Equal(other Tally) bool {
+	if t.QuestionRefer != other.QuestionRefer || t.ChoiceRefer != other.ChoiceRefer ||
+		t.Count != other.Count || t.Respondents != other.Respondents || t.RankSum != other.RankSum {
+		return false
+	}
+	if math.Abs(t.NumberSum-other.NumberSum) > 1e-9*math.Max(1, math.Abs(other.NumberSum)) {
+		return false
+	}
+
+	return equalNumber(t.NumberMin, other.NumberMin) && equalNumber(t.NumberMax, other.NumberMax)
+}
+
+func equalNumber(a, b *float64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return *a == *b
+}
+
+// DiffTallies compares the stored tallies with the ones counted from the votes.
+// A tally missing on either side counts as zero.
+func DiffTallies(stored, counted []Tally) []TallyDrift {
+	drift := make([]TallyDrift, 0)
+
+	storedByKey := make(map[tallyKey]Tally)
+	for _, tally := range stored {
+		storedByKey[tallyKey{tally.QuestionRefer, tally.ChoiceRefer}] = tally
+	}
+
+	for _, tally := range counted {
+		key := tallyKey{tally.QuestionRefer, tally.ChoiceRefer}
+		storedTally, ok := storedByKey[key]
+		if !ok {
+			storedTally = Tally{QuestionRefer: tally.QuestionRefer, ChoiceRefer: tally.ChoiceRefer}
+		}
+		if !storedTally.Equal(tally) {
+			drift = append(drift, TallyDrift{storedTally, tally})
+		}
+		delete(storedByKey, key)
+	}
+
+	// tallies of votes that are gone
+	for _, tally := range stored {
+		key := tallyKey{tally.QuestionRefer, tally.ChoiceRefer}
+		if _, ok := storedByKey[key]; !ok {
+			continue
+		}
+		empty := Tally{QuestionRefer: tally.QuestionRefer, ChoiceRefer: tally.ChoiceRefer}
+		if !tally.Equal(empty) {
+			drift = append(drift, TallyDrift{tally, empty})
+		}
+	}
+
+	return drift
+}
@@ -0,0 +1,58 @@
+package model
+
+import (
+	"testing"
+)
+
+func TestNewTallies(t *testing.T) {
+	choiceA, choiceB := uint(1), uint(2)
+	number := 4.5
+	votes := []Vote{
+		{QuestionRefer: 1, ChoiceRefer: &choiceA, Rank: 1},
+		{QuestionRefer: 1, ChoiceRefer: &choiceB, Rank: 2},
+		{QuestionRefer: 2, Number: &number},
+	}
+
+	tallies := NewTallies(votes)
+	want := []Tally{
+		{QuestionRefer: 1, Respondents: 1},
+		{QuestionRefer: 1, ChoiceRefer: 1, Count: 1, RankSum: 1},
+		{QuestionRefer: 1, ChoiceRefer: 2, Count: 1, RankSum: 2},
+		{QuestionRefer: 2, Count: 1, Respondents: 1, NumberSum: 4.5, NumberMin: &number, NumberMax: &number},
+	}
+	if len(tallies) != len(want) {
+		t.Fatalf("NewTallies() = %+v, want %+v", tallies, want)
+	}
+	for i := range want {
+		if !tallies[i].Equal(want[i]) {
+			t.Errorf("NewTallies()[%d] = %+v, want %+v", i, tallies[i], want[i])
+		}
+	}
+}
+
+func TestDiffTallies(t *testing.T) {
+	stored := []Tally{
+		{QuestionRefer: 1, Respondents: 2},
+		{QuestionRefer: 1, ChoiceRefer: 1, Count: 3},
+		{QuestionRefer: 3, ChoiceRefer: 5, Count: 1},
+	}
+	counted := []Tally{
+		{QuestionRefer: 1, Respondents: 2},
+		{QuestionRefer: 1, ChoiceRefer: 1, Count: 2},
+		{QuestionRefer: 1, ChoiceRefer: 2, Count: 1},
+	}
+
+	drift := DiffTallies(stored, counted)
+	if len(drift) != 3 {
+		t.Fatalf("DiffTallies() = %+v, want 3 drifted tallies", drift)
+	}
+	if drift[0].Stored.Count != 3 || drift[0].Counted.Count != 2 {
+		t.Errorf("DiffTallies()[0] = %+v, want the stored count 3 recounted as 2", drift[0])
+	}
+	if drift[1].Stored.Count != 0 || drift[1].Counted.Count != 1 {
+		t.Errorf("DiffTallies()[1] = %+v, want a missing tally counted as 1", drift[1])
+	}
+	if drift[2].Stored.Count != 1 || drift[2].Counted.Count != 0 {
+		t.Errorf("DiffTallies()[2] = %+v, want a tally without votes counted as 0", drift[2])
+	}
+}
@@ -0,0 +1,75 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SurveyTemplate keeps the definition of a survey to start new drafts from. Personal
+// templates are only listed to their owner, organization templates are published by
+// employees and listed to everyone.
+type SurveyTemplate struct {
+	gorm.Model
+	Name         string
+	UserRefer    *uint `gorm:"index" json:",omitempty"` // owner of a personal template
+	PublishedBy  *uint `json:",omitempty"`              // employee that published an organization template
+	Organization bool
+	Content      string            `json:"-"`                   // survey definition as JSON
+	Definition   *SurveyDefinition `gorm:"-" json:",omitempty"` // only loaded for a single template
+}
+
+// TemplateRequest names the template saved from a survey.
+type TemplateRequest struct {
+	Name string `binding:"required"`
+}
+
+// CloneRequest schedules a survey copied from another survey or from a template.
+type CloneRequest struct {
+	DateStart time.Time `binding:"required"`
+	DateEnd   time.Time `binding:"required"`
+}
+
+// NewSurveyTemplate stores a survey definition as a template, its dates are left
+// out as every survey started from it is scheduled anew.
+func NewSurveyTemplate(name string, definition *SurveyDefinition) (*SurveyTemplate, error) {
+	undated := *definition
+	undated.DateStart, undated.DateEnd = time.Time{}, time.Time{}
+
+	content, err := json.Marshal(&undated)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SurveyTemplate{Name: name, Content: string(content), Definition: &undated}, nil
+}
+
+// ValidateOrganizationTemplate checks that the survey may be published as an organization
+// template. Only confirmed surveys that are open to everyone may be: drafts and surveys
+// under review are not shown to anyone but their owner and reviewers yet.
+func (s *Survey) ValidateOrganizationTemplate() error {
+	if s.ConfirmStatus != ConfirmStatusAccepted && s.ConfirmStatus != ConfirmStatusArchived {
+		return errors.New("only confirmed surveys can be published as templates")
+	}
+	if s.Private {
+		return errors.New("private surveys can not be published as templates")
+	}
+
+	return nil
+}
+
+// Decode restores the definition of a stored template.
+func (t *SurveyTemplate) Decode() (err error) {
+	t.Definition, err = ParseSurveyDefinition([]byte(t.Content), DefinitionFormatJSON)
+	return err
+}
+
+// Scheduled returns a copy of the definition with the dates of the request.
+func (d *SurveyDefinition) Scheduled(request CloneRequest) *SurveyDefinition {
+	scheduled := *d
+	scheduled.DateStart, scheduled.DateEnd = request.DateStart, request.DateEnd
+
+	return &scheduled
+}
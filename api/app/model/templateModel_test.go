@@ -0,0 +1,66 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewSurveyTemplate(t *testing.T) {
+	definition := &SurveyDefinition{
+		Version:     DefinitionVersion,
+		Subject:     "Quarterly satisfaction",
+		Description: "d",
+		DateStart:   time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+		DateEnd:     time.Date(2022, 2, 1, 0, 0, 0, 0, time.UTC),
+		Questions:   []QuestionDefinition{{Text: "How satisfied are you?", Type: QuestionTypeLikert, Choices: []string{"1", "2", "3"}}},
+	}
+
+	template, err := NewSurveyTemplate("Quarterly", definition)
+	if err != nil {
+		t.Fatalf("NewSurveyTemplate() error = %v", err)
+	}
+	if definition.DateStart.IsZero() {
+		t.Error("NewSurveyTemplate() changed the dates of the survey definition")
+	}
+
+	stored := SurveyTemplate{Content: template.Content}
+	if err = stored.Decode(); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if !stored.Definition.DateStart.IsZero() || !stored.Definition.DateEnd.IsZero() {
+		t.Errorf("template kept the dates %v - %v", stored.Definition.DateStart, stored.Definition.DateEnd)
+	}
+	if stored.Definition.Subject != definition.Subject || len(stored.Definition.Questions) != 1 {
+		t.Errorf("Decode() = %+v, want the survey definition", stored.Definition)
+	}
+
+	request := CloneRequest{DateStart: time.Date(2022, 4, 1, 0, 0, 0, 0, time.UTC), DateEnd: time.Date(2022, 5, 1, 0, 0, 0, 0, time.UTC)}
+	scheduled := stored.Definition.Scheduled(request)
+	if !scheduled.DateStart.Equal(request.DateStart) || !scheduled.DateEnd.Equal(request.DateEnd) {
+		t.Errorf("Scheduled() dates = %v - %v", scheduled.DateStart, scheduled.DateEnd)
+	}
+	if !stored.Definition.DateStart.IsZero() {
+		t.Error("Scheduled() changed the template definition")
+	}
+}
+
+func TestSurveyValidateOrganizationTemplate(t *testing.T) {
+	tests := []struct {
+		name    string
+		survey  Survey
+		wantErr bool
+	}{
+		{"draft of another user", Survey{UserRefer: 7, ConfirmStatus: ConfirmStatusDraft}, true},
+		{"waiting", Survey{UserRefer: 7, ConfirmStatus: ConfirmStatusWaiting}, true},
+		{"private", Survey{UserRefer: 7, ConfirmStatus: ConfirmStatusAccepted, Private: true}, true},
+		{"confirmed", Survey{UserRefer: 7, ConfirmStatus: ConfirmStatusAccepted}, false},
+		{"archived", Survey{UserRefer: 7, ConfirmStatus: ConfirmStatusArchived}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.survey.ValidateOrganizationTemplate(); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateOrganizationTemplate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -3,7 +3,28 @@ package model
 import "gorm.io/gorm"
 
 type Vote struct {
-	gorm.Model  `fake:"skip"`
-	UserRefer   uint `gorm:"index:idx_vote,unique" binding:"required" fake:"{number:1,50}"`
-	ChoiceRefer uint `gorm:"index:idx_vote,unique" binding:"required" fake:"skip"`
+	gorm.Model    `fake:"skip"`
+	UserRefer     *uint    `gorm:"index:idx_vote,unique" binding:"required" fake:"{number:1,50}"` // null on anonymous ballots
+	QuestionRefer uint     `gorm:"index" fake:"skip"`
+	ChoiceRefer   *uint    `gorm:"index:idx_vote,unique" fake:"skip"` // null for numeric and text answers
+	Rank          uint     `fake:"skip"`                              // 1-based position for ranking questions
+	Number        *float64 `fake:"skip"`
+	Text          *string  `fake:"skip"`
+	BallotKey     string   `gorm:"index" json:"-" fake:"skip"` // groups the votes of an anonymous ballot
+}
+
+// Response records that a user answered a question, its unique index allows a
+// single answer set per user and question.
+type Response struct {
+	gorm.Model    `fake:"skip"`
+	UserRefer     uint `gorm:"uniqueIndex:idx_response" fake:"skip"`
+	QuestionRefer uint `gorm:"uniqueIndex:idx_response" fake:"skip"`
+}
+
+// Answer is the answer to one question submitted with a vote.
+type Answer struct {
+	QuestionID uint     `binding:"required"`
+	Choices    []uint   // choice indices, in preference order for ranking questions
+	Number     *float64 // numeric questions
+	Text       *string  // free text questions
 }
@@ -1,11 +1,20 @@
 package repository
 
 import (
+	"crypto/rand"
 	"database/sql"
+	appError "dou-survey/app/error"
 	"dou-survey/app/model"
 	"dou-survey/internal/logger"
 	"dou-survey/internal/storage"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // billingRepository handles communication with the survey store
@@ -17,21 +26,59 @@ type SurveyRepository struct {
 //SurveyRepositoryInterface define the survey repository interface methods
 type SurveyRepositoryInterface interface {
 	GetConfirmed(surveyID uint) (confirmStatus model.ConfirmStatus, err error)
-	ChoiceVotersInfo(choiceID uint) (voters []model.UserReduced, err error)
-	Vote(userID, surveyID uint, votes []uint) (created []model.Vote, err error)
+	FindSurveyIDByChoice(choiceID uint) (surveyID uint, err error)
+	Crosstab(surveyID, choiceID uint, ageAt time.Time) (rows []model.CrosstabRow, err error)
+	DemographicCrosstab(surveyID, choiceID uint) (rows []model.CrosstabRow, err error)
+	Vote(userID, surveyID uint, answers []model.Answer, token string, now time.Time) (created []model.Vote, receipt string, err error)
 	VotedAlready(userID, surveyID uint) (voted bool, err error)
-	ListWaitingConfirmation(limit, offset uint) (surveys []model.Survey, err error)
-	ListActive(limit, offset uint) (surveys []model.Survey, err error)
+	ListWaitingConfirmation(reviewerID, limit, offset uint) (surveys []model.Survey, err error)
+	CreateModeration(moderation *model.Moderation) error
+	ListActive(limit, offset uint, voter *model.User) (surveys []model.Survey, err error)
 	ListResults(limit, offset uint) (surveys []model.Survey, err error)
 	CountWaitingConfirmation() (count int, err error)
-	CountActive() (count int, err error)
+	SummarizeWaitingConfirmation(overdueBefore time.Time) (queue *model.ReviewQueue, err error)
+	CountActive(voter *model.User) (count int, err error)
+	CountEligibleUsers(eligibility *model.Eligibility, now time.Time) (count int, err error)
+	ListQuotas(surveyID uint) (quotas []model.Quota, err error)
+	CreateInvitations(invitations []model.Invitation) error
+	ListInvitations(surveyID uint) (invitations []model.Invitation, err error)
+	RevokeInvitation(surveyID, invitationID uint, now time.Time) error
+	SaveDraft(draft *model.DraftBallot) error
+	FindDraft(userID, surveyID uint) (draft *model.DraftBallot, err error)
+	DeleteClosedDrafts(now time.Time) (deleted int64, err error)
+	CreateTemplate(template *model.SurveyTemplate) error
+	ListTemplates(userID uint) (templates []model.SurveyTemplate, err error)
+	FindTemplate(id uint) (template *model.SurveyTemplate, err error)
+	DeleteTemplate(id uint) error
 	CountResults() (count int, err error)
 	FindByIDReduced(id uint) (survey *model.Survey, err error)
-	FindByIDWithVotes(id uint) (survey *model.Survey, err error)
+	FindByIDWithResults(id uint) (survey *model.Survey, err error)
+	ListVotes(surveyID uint) (votes []model.Vote, err error)
+	StreamRespondents(surveyID uint, fn func(user *model.User) error) error
+	StreamResponses(surveyID uint, fn func(row *model.ResponseRow) error) error
+	ReconcileTallies() (drift []model.TallyDrift, err error)
+	ListBallotVotes(userID, surveyID uint) (votes []model.Vote, err error)
+	CountReplacedBallots(userID, surveyID uint) (count uint, err error)
+	ListBallotLog(surveyID uint) (entries []model.BallotLogEntry, err error)
+	FindBallotLogEntry(receipt string) (entry *model.BallotLogEntry, replaced bool, err error)
+	ChainPendingBallots(surveyID uint) error
+	PublishBallotRoot(surveyID uint, root string) error
+	SetPreviousWave(surveyID uint, previousID *uint) error
+	ListUnpublishedBallotRoots() (surveyIDs []uint, err error)
 	FindByIDWithoutVotes(id uint) (survey *model.Survey, err error)
 	CountQuestion(id uint) (count int, err error)
 	RemoveByID(id uint) error
 	UpdateByID(id uint, survey model.Survey) error
+	ReplaceDraft(id uint, from model.ConfirmStatus, survey *model.Survey, records ...interface{}) (replaced *model.Survey, err error)
+	Transition(id uint, from, to model.ConfirmStatus, records ...interface{}) error
+	UpdateDraft(id uint, from model.ConfirmStatus, surveyUpdate model.Survey, records ...interface{}) error
+	Claim(id, reviewerID uint, until time.Time) error
+	Release(id, reviewerID uint) error
+	SetSensitive(id uint, sensitive bool) error
+	ListModerations(surveyIDs ...uint) (moderations []model.Moderation, err error)
+	ListPrivacyReleases(surveyID uint) (releases []model.PrivacyRelease, err error)
+	CreatePrivacyRelease(release *model.PrivacyRelease) error
+	ListTransitions(id uint) (transitions []model.SurveyTransition, err error)
 	CreateSurvey(create *model.Survey) (survey *model.Survey, err error)
 }
 
@@ -62,474 +109,1321 @@ func (r *SurveyRepository) GetConfirmed(surveyID uint) (confirmStatus model.Conf
 	return confirmStatus, nil
 }
 
-// FindByID implements the method to find a survey from the store
-func (r *SurveyRepository) ChoiceVotersInfo(choiceID uint) (voters []model.UserReduced, err error) {
-	rows, err := r.db.Raw("SELECT v.id AS vote_id, u.id AS user_id, u.birth_sex, u.gender_identity, u.birth_date, u.is_resident FROM (SELECT * FROM `choices` WHERE `choices`.`id` = ?) AS c JOIN votes AS v ON v.choice_refer = c.id JOIN users AS u ON u.id = v.user_refer ORDER BY c.id", choiceID).Rows()
+// FindSurveyIDByChoice implements the method to find the survey a choice belongs to
+func (r *SurveyRepository) FindSurveyIDByChoice(choiceID uint) (surveyID uint, err error) {
+	rows, err := r.db.Raw("SELECT q.survey_refer FROM `choices` AS c JOIN questions AS q ON q.id = c.question_refer WHERE `c`.`id` = ? AND `c`.`deleted_at` IS NULL", choiceID).Rows()
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
 
 	defer rows.Close()
-	// Values to load into
-	voters = make([]model.UserReduced, 0)
 
 	for rows.Next() {
-		var voteID sql.NullInt64
-		var userID sql.NullInt64
-		user := &model.UserReduced{}
-
-		err = rows.Scan(&voteID, &userID, &user.BirthSex, &user.GenderIdentity, &user.BirthDate, &user.IsResident)
+		err = rows.Scan(&surveyID)
 		if err != nil {
-			return nil, err
+			return 0, err
 		}
-
-		voters = append(voters, *user)
 	}
 
-	return voters, nil
+	return surveyID, nil
 }
 
-// FindByID implements the method to find a survey from the store
-func (r *SurveyRepository) Vote(userID, surveyID uint, votes []uint) (created []model.Vote, err error) {
-	confirmStatus, err := r.GetConfirmed(surveyID)
+// crosstabQuery counts the votes of every choice per demographic group and the
+// respondents of every question per group, ranking questions count first preferences
+const crosstabQuery = `WITH answers AS (
+	SELECT q.id AS question_id, c.id AS choice_id, u.id AS user_id, u.birth_sex, u.gender_identity,
+		CASE WHEN u.is_resident THEN 'true' ELSE 'false' END AS is_resident,
+		CAST(strftime('%Y', @age_at) AS INTEGER) - CAST(strftime('%Y', u.birth_date) AS INTEGER)
+			- (strftime('%m-%d', @age_at) < strftime('%m-%d', u.birth_date)) AS age
+	FROM questions AS q
+	JOIN choices AS c ON c.question_refer = q.id AND c.deleted_at IS NULL
+	JOIN votes AS v ON v.choice_refer = c.id AND v.deleted_at IS NULL
+	JOIN users AS u ON u.id = v.user_refer
+	WHERE q.survey_refer = @survey AND q.deleted_at IS NULL AND (@choice = 0 OR c.id = @choice) AND (q.type <> @ranking OR v.rank = 1)
+), grouped AS (
+	SELECT question_id, choice_id, user_id, 'Total' AS dimension, 'all' AS grp FROM answers
+	UNION ALL SELECT question_id, choice_id, user_id, 'BirthSex', birth_sex FROM answers
+	UNION ALL SELECT question_id, choice_id, user_id, 'GenderIdentity', gender_identity FROM answers
+	UNION ALL SELECT question_id, choice_id, user_id, 'AgeBand', CASE
+		WHEN age < 18 THEN '0-17' WHEN age < 25 THEN '18-24' WHEN age < 35 THEN '25-34' WHEN age < 45 THEN '35-44'
+		WHEN age < 55 THEN '45-54' WHEN age < 65 THEN '55-64' ELSE '65+' END FROM answers
+	UNION ALL SELECT question_id, choice_id, user_id, 'IsResident', is_resident FROM answers
+), respondents AS (
+	SELECT question_id, dimension, grp, count(DISTINCT user_id) AS respondents FROM grouped GROUP BY question_id, dimension, grp
+), cells AS (
+	SELECT question_id, choice_id, dimension, grp, count(1) AS votes FROM grouped GROUP BY question_id, choice_id, dimension, grp
+)
+SELECT c.question_id, c.choice_id, c.dimension, c.grp, c.votes, r.respondents, ROUND(c.votes * 100.0 / r.respondents, 2)
+FROM cells AS c JOIN respondents AS r ON r.question_id = c.question_id AND r.dimension = c.dimension AND r.grp = c.grp
+ORDER BY c.question_id, c.dimension, c.grp, c.choice_id`
+
+// DemographicCrosstab implements the method to read the crosstab of an anonymous survey,
+// or only of one of its choices if choiceID is not 0, from its demographic tallies
+func (r *SurveyRepository) DemographicCrosstab(surveyID, choiceID uint) (crosstab []model.CrosstabRow, err error) {
+	rows, err := r.db.Raw("SELECT c.question_refer, c.choice_refer, c.dimension, c.grp, c.count, t.count, ROUND(c.count * 100.0 / t.count, 2) "+
+		"FROM `demographic_tallies` AS c JOIN `demographic_tallies` AS t ON t.question_refer = c.question_refer AND t.choice_refer = 0 AND t.dimension = c.dimension AND t.grp = c.grp "+
+		"JOIN questions AS q ON q.id = c.question_refer "+
+		"WHERE q.survey_refer = ? AND q.deleted_at IS NULL AND c.choice_refer <> 0 AND (? = 0 OR c.choice_refer = ?) AND c.count > 0 AND t.count > 0 "+
+		"ORDER BY c.question_refer, c.dimension, c.grp, c.choice_refer", surveyID, choiceID, choiceID).Rows()
 	if err != nil {
 		return nil, err
 	}
-	if confirmStatus != "confirmed" {
-		return nil, errors.New("survey must get confirmed before you can vote")
+
+	defer rows.Close()
+	// Values to load into
+	crosstab = make([]model.CrosstabRow, 0)
+
+	for rows.Next() {
+		row := model.CrosstabRow{}
+		err = rows.Scan(&row.QuestionID, &row.ChoiceID, &row.Dimension, &row.Group, &row.Count, &row.Respondents, &row.Percentage)
+		if err != nil {
+			return nil, err
+		}
+
+		crosstab = append(crosstab, row)
 	}
 
-	rows, err := r.db.Raw("SELECT q.id as question_id, c.id AS choice_id FROM (SELECT * FROM `surveys` WHERE `surveys`.`id` = ?) AS s JOIN questions AS q ON q.survey_refer = s.id JOIN choices AS c ON c.question_refer = q.id ORDER BY c.id", surveyID).Rows()
+	return crosstab, nil
+}
+
+// Crosstab implements the method to aggregate the votes of a survey, or only of one of
+// its choices if choiceID is not 0, by the demographics of the voters. Ages are computed
+// at the given time
+func (r *SurveyRepository) Crosstab(surveyID, choiceID uint, ageAt time.Time) (crosstab []model.CrosstabRow, err error) {
+	rows, err := r.db.Raw(crosstabQuery, map[string]interface{}{
+		"survey":  surveyID,
+		"choice":  choiceID,
+		"age_at":  ageAt.UTC(),
+		"ranking": model.QuestionTypeRanking,
+	}).Rows()
 	if err != nil {
 		return nil, err
 	}
 
 	defer rows.Close()
 	// Values to load into
-	questions := make([]model.Question, 0)
+	crosstab = make([]model.CrosstabRow, 0)
 
 	for rows.Next() {
-		var questionID sql.NullInt64
-		var choiceID sql.NullInt64
-
-		err = rows.Scan(&questionID, &choiceID)
+		row := model.CrosstabRow{}
+		err = rows.Scan(&row.QuestionID, &row.ChoiceID, &row.Dimension, &row.Group, &row.Count, &row.Respondents, &row.Percentage)
 		if err != nil {
 			return nil, err
 		}
-		if !choiceID.Valid || !questionID.Valid {
-			continue
+
+		crosstab = append(crosstab, row)
+	}
+
+	return crosstab, nil
+}
+
+// Vote implements the method to store a ballot. The responses, the status and answer
+// checks, the votes and their tallies share one transaction, the responses table
+// rejects a second answer set to any question. If the survey allows ballot changes
+// a previous ballot of the user is moved to the history and replaced. Every ballot is
+// appended to the ballot log of the survey, its log hash is the receipt of the voter
+func (r *SurveyRepository) Vote(userID, surveyID uint, answers []model.Answer, token string, now time.Time) (created []model.Vote, receipt string, err error) {
+	// anonymity can not change once the survey is confirmed
+	survey, err := r.FindByIDReduced(surveyID)
+	if err != nil {
+		return nil, "", err
+	}
+	// so can its eligibility criteria
+	if survey.Eligibility.Restricted() {
+		user := &model.User{}
+		if err = r.db.First(user, userID).Error; err != nil {
+			return nil, "", err
 		}
+		if !survey.Eligibility.Admits(user, now) {
+			return nil, "", appError.ErrNotEligible
+		}
+	}
+	if survey.Anonymous {
+		return r.voteAnonymously(userID, surveyID, answers, token, now)
+	}
 
-		questionIDInt := uint(questionID.Int64)
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		tr := r.withTx(tx)
 
-		// Check if surveys exists in result array
-		var question *model.Question
-		isNewQuestion := true
-		for i, s := range questions {
-			if s.ID == questionIDInt {
-				question = &questions[i]
-				isNewQuestion = false
+		// claim the questions first, a concurrent ballot of the same user then
+		// waits for this transaction and finds the responses taken
+		responses := make([]model.Response, 0)
+		for _, answer := range answers {
+			responses = append(responses, model.Response{UserRefer: userID, QuestionRefer: answer.QuestionID})
+		}
+		previous := false
+		if len(responses) > 0 {
+			result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&responses)
+			if err := result.Error; err != nil {
+				return err
 			}
+			previous = int(result.RowsAffected) != len(responses)
 		}
 
-		if isNewQuestion {
-			question = &model.Question{}
-			question.ID = questionIDInt
-			question.Choices = make([]model.Choice, 0)
-			questions = append(questions, *question)
-			question = &questions[len(questions)-1]
+		reduced, survey, err := tr.ballotSurvey(surveyID, answers, now)
+		if err != nil {
+			return err
+		}
+
+		if !previous {
+			// ballots cast before responses were recorded
+			if previous, err = tr.VotedAlready(userID, surveyID); err != nil {
+				return err
+			}
+		}
+		replaces := ""
+		if previous {
+			if !reduced.BallotChanges {
+				return appError.ErrVotedAlready
+			}
+			if replaces, err = tr.replaceBallot(userID, surveyID, responses); err != nil {
+				return err
+			}
+		} else {
+			if reduced.Private {
+				if err = redeemInvitation(tx, surveyID, userID, token, now); err != nil {
+					return err
+				}
+			}
+			if err = fillQuotas(tx, reduced, userID, now); err != nil {
+				return err
+			}
+		}
+
+		created = make([]model.Vote, 0)
+		for _, answer := range answers {
+			votes, err := survey.Question(answer.QuestionID).VotesFor(userID, answer)
+			if err != nil {
+				return err
+			}
+
+			created = append(created, votes...)
 		}
 
-		choiceIDInt := uint(choiceID.Int64)
-		choice := model.Choice{}
-		choice.ID = choiceIDInt
+		if receipt, err = appendBallotLog(tx, surveyID, created, replaces); err != nil {
+			return err
+		}
+		if err = tx.Create(&created).Error; err != nil {
+			return err
+		}
+		if err = deleteDraft(tx, userID, surveyID); err != nil {
+			return err
+		}
 
-		question.Choices = append(question.Choices, choice)
+		return addTallies(tx, model.NewTallies(created))
+	})
+	if err != nil {
+		return nil, "", err
 	}
 
-	created = make([]model.Vote, 0)
+	return created, receipt, nil
+}
 
-	// loop for validation
-	for i, s := range questions {
-		voteIndex := int(votes[i])
-		if len(s.Choices) <= voteIndex {
-			return nil, errors.New("vote index out of bounds")
+// voteAnonymously stores a ballot without its voter. The participation of the user
+// is recorded apart from the votes, the demographics of the voter only go into the
+// demographic tallies and the vote times are kept to the day. Votes get random keys
+// and the ballot waits for voting to close before it is logged, so neither follows
+// the order of the participations
+func (r *SurveyRepository) voteAnonymously(userID, surveyID uint, answers []model.Answer, token string, now time.Time) (created []model.Vote, receipt string, err error) {
+	castOn := now.Truncate(24 * time.Hour)
+
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		tr := r.withTx(tx)
+
+		// claim the survey first, a concurrent ballot of the same user then
+		// waits for this transaction and finds the participation taken
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&model.Participation{UserRefer: userID, SurveyRefer: surveyID})
+		if err := result.Error; err != nil {
+			return err
+		}
+		if result.RowsAffected == 0 {
+			return appError.ErrVotedAlready
 		}
 
-		vote := model.Vote{
-			UserRefer:   userID,
-			ChoiceRefer: s.Choices[voteIndex].ID,
+		reduced, survey, err := tr.ballotSurvey(surveyID, answers, now)
+		if err != nil {
+			return err
 		}
 
-		created = append(created, vote)
-	}
+		user := &model.User{}
+		if err = tx.First(user, userID).Error; err != nil {
+			return err
+		}
+		if reduced.Private {
+			if err = redeemInvitation(tx, surveyID, userID, token, castOn); err != nil {
+				return err
+			}
+		}
+		if err = fillQuotas(tx, reduced, userID, now); err != nil {
+			return err
+		}
 
-	// create
-	r.logger.Infof("VOTE")
-	r.logger.Infof("%#v", created)
-	result := r.db.Create(&created)
-	r.logger.Infof("VOTE AFTER CREATE")
-	r.logger.Infof("%#v", created)
+		created = make([]model.Vote, 0)
+		for _, answer := range answers {
+			votes, err := survey.Question(answer.QuestionID).VotesFor(userID, answer)
+			if err != nil {
+				return err
+			}
 
-	if err = result.Error; err != nil {
-		return nil, err
-	}
+			for i := range votes {
+				if votes[i].ID, err = randomID(); err != nil {
+					return err
+				}
+				votes[i].UserRefer = nil
+				votes[i].CreatedAt = castOn
+				votes[i].UpdatedAt = castOn
+			}
+			created = append(created, votes...)
+		}
 
-	return created, nil
-}
+		if receipt, err = pendBallot(tx, surveyID, created); err != nil {
+			return err
+		}
+		if err = tx.Create(&created).Error; err != nil {
+			return err
+		}
+		if err = deleteDraft(tx, userID, surveyID); err != nil {
+			return err
+		}
+		if err = addTallies(tx, model.NewTallies(created)); err != nil {
+			return err
+		}
 
-// FindByID implements the method to find a survey from the store
-func (r *SurveyRepository) VotedAlready(userID, surveyID uint) (voted bool, err error) {
-	rows, err := r.db.Raw("SELECT v.id AS vote_id FROM (SELECT * FROM `surveys` WHERE `surveys`.`id` = ?) AS s JOIN questions AS q ON q.survey_refer = s.id JOIN choices AS c ON c.question_refer = q.id LEFT JOIN votes AS v ON v.choice_refer = c.id WHERE v.user_refer = ? ORDER BY c.id", surveyID, userID).Rows()
+		return addDemographicTallies(tx, model.NewDemographicTallies(created, model.VoterGroups(user, reduced.DateEnd)))
+	})
 	if err != nil {
-		return true, err
+		return nil, "", err
 	}
 
-	defer rows.Close()
-	voted = false
+	return created, receipt, nil
+}
 
-	for rows.Next() {
-		voted = true
-		break
+// appendBallotLog chains the votes of a ballot into the log of the survey and marks
+// the votes with the receipt
+func appendBallotLog(tx *gorm.DB, surveyID uint, votes []model.Vote, replaces string) (receipt string, err error) {
+	nonce := make([]byte, 16)
+	if _, err = rand.Read(nonce); err != nil {
+		return "", err
 	}
 
-	return voted, nil
-}
+	last := make([]model.BallotLogEntry, 0)
+	if err = tx.Where("survey_refer = ?", surveyID).Order("sequence DESC").Limit(1).Find(&last).Error; err != nil {
+		return "", err
+	}
+	var previous *model.BallotLogEntry
+	if len(last) > 0 {
+		previous = &last[0]
+	}
 
-// FindByID implements the method to find a survey from the store
-func (r *SurveyRepository) CountWaitingConfirmation() (count int, err error) {
-	rows, err := r.db.Raw("SELECT count(1) FROM `surveys` AS s WHERE `s`.`deleted_at` IS NULL AND `s`.`confirm_status` = 'waiting'").Rows()
+	entry, err := model.NewBallotLogEntry(surveyID, previous, model.NewLoggedBallot(hex.EncodeToString(nonce), replaces, votes))
 	if err != nil {
-		return -1, err
+		return "", err
+	}
+	if err = tx.Create(entry).Error; err != nil {
+		return "", err
 	}
 
-	defer rows.Close()
+	for i := range votes {
+		votes[i].BallotKey = entry.Hash
+	}
 
-	for rows.Next() {
-		err = rows.Scan(&count)
-		if err != nil {
-			return -1, err
-		}
+	return entry.Hash, nil
+}
+
+// randomID draws a primary key that does not follow the insertion order
+func randomID() (uint, error) {
+	key := make([]byte, 8)
+	if _, err := rand.Read(key); err != nil {
+		return 0, err
 	}
 
-	return count, nil
+	// stays within the integers JSON clients read exactly
+	return uint(binary.BigEndian.Uint64(key)>>11) + 1, nil
 }
 
-// FindByID implements the method to find a survey from the store
-func (r *SurveyRepository) CountActive() (count int, err error) {
-	rows, err := r.db.Raw("SELECT count(1) FROM `surveys` AS s WHERE `s`.`deleted_at` IS NULL AND `s`.`confirm_status` = 'confirmed' AND date('now') BETWEEN `s`.`date_start` AND `s`.`date_end`").Rows()
-	if err != nil {
-		return -1, err
+// pendBallot keeps an anonymous ballot out of the log until voting closes and marks
+// its votes with the receipt
+func pendBallot(tx *gorm.DB, surveyID uint, votes []model.Vote) (receipt string, err error) {
+	nonce := make([]byte, 16)
+	if _, err = rand.Read(nonce); err != nil {
+		return "", err
 	}
+	receipt = hex.EncodeToString(nonce)
 
-	defer rows.Close()
+	content, err := json.Marshal(model.NewLoggedBallot(receipt, "", votes))
+	if err != nil {
+		return "", err
+	}
+	if err = tx.Create(&model.PendingBallot{Receipt: receipt, SurveyRefer: surveyID, Ballot: string(content)}).Error; err != nil {
+		return "", err
+	}
 
-	for rows.Next() {
-		err = rows.Scan(&count)
-		if err != nil {
-			return -1, err
-		}
+	for i := range votes {
+		votes[i].BallotKey = receipt
 	}
 
-	return count, nil
+	return receipt, nil
 }
 
-// FindByID implements the method to find a survey from the store
-func (r *SurveyRepository) CountResults() (count int, err error) {
-	rows, err := r.db.Raw("SELECT count(1) FROM `surveys` WHERE `surveys`.`deleted_at` IS NULL AND `surveys`.`confirm_status` = 'confirmed' AND NOT date('now') BETWEEN `surveys`.`date_start` AND `surveys`.`date_end`").Rows()
-	if err != nil {
-		return -1, err
-	}
+// ChainPendingBallots implements the method to append the pending anonymous ballots of
+// a closed survey to its log, ordered by their random receipts
+func (r *SurveyRepository) ChainPendingBallots(surveyID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		pending := make([]model.PendingBallot, 0)
+		if err := tx.Where("survey_refer = ?", surveyID).Order("receipt").Find(&pending).Error; err != nil {
+			return err
+		}
+		if len(pending) == 0 {
+			return nil
+		}
 
-	defer rows.Close()
+		last := make([]model.BallotLogEntry, 0)
+		if err := tx.Where("survey_refer = ?", surveyID).Order("sequence DESC").Limit(1).Find(&last).Error; err != nil {
+			return err
+		}
+		var previous *model.BallotLogEntry
+		if len(last) > 0 {
+			previous = &last[0]
+		}
 
-	for rows.Next() {
-		err = rows.Scan(&count)
-		if err != nil {
-			return -1, err
+		for _, ballot := range pending {
+			logged := model.LoggedBallot{}
+			if err := json.Unmarshal([]byte(ballot.Ballot), &logged); err != nil {
+				return err
+			}
+
+			entry, err := model.NewBallotLogEntry(surveyID, previous, logged)
+			if err != nil {
+				return err
+			}
+			entry.Receipt = ballot.Receipt
+			if err = tx.Create(entry).Error; err != nil {
+				return err
+			}
+			previous = entry
 		}
-	}
 
-	return count, nil
+		return tx.Where("survey_refer = ?", surveyID).Delete(&model.PendingBallot{}).Error
+	})
 }
 
-// FindByID implements the method to find a survey from the store
-func (r *SurveyRepository) ListWaitingConfirmation(limit, offset uint) (surveys []model.Survey, err error) {
-	rows, err := r.db.Raw("SELECT s.id, s.user_refer, s.subject, s.description, s.date_start, s.date_end FROM `surveys` AS s WHERE `s`.`deleted_at` IS NULL AND `s`.`confirm_status` = 'waiting' ORDER BY `s`.`id` LIMIT ? OFFSET ?", limit, offset).Rows()
-	if err != nil {
+// ListBallotLog implements the method to list the ballot log of a survey in sequence
+func (r *SurveyRepository) ListBallotLog(surveyID uint) (entries []model.BallotLogEntry, err error) {
+	entries = make([]model.BallotLogEntry, 0)
+	if err = r.db.Where("survey_refer = ?", surveyID).Order("sequence").Find(&entries).Error; err != nil {
 		return nil, err
 	}
 
-	defer rows.Close()
-	// Values to load into
-	surveys = make([]model.Survey, 0)
-
-	for rows.Next() {
-		survey := &model.Survey{}
+	return entries, nil
+}
 
-		err = rows.Scan(&survey.ID, &survey.UserRefer, &survey.Subject, &survey.Description, &survey.DateStart, &survey.DateEnd)
-		if err != nil {
-			return nil, err
+// FindBallotLogEntry implements the method to find the log entry of a receipt, and
+// whether a later ballot replaced it. A pending anonymous ballot has no sequence yet
+func (r *SurveyRepository) FindBallotLogEntry(receipt string) (entry *model.BallotLogEntry, replaced bool, err error) {
+	entries := make([]model.BallotLogEntry, 0)
+	if err = r.db.Where("hash = ? OR receipt = ?", receipt, receipt).Find(&entries).Error; err != nil {
+		return nil, false, err
+	}
+	if len(entries) == 0 {
+		pending := make([]model.PendingBallot, 0)
+		if err = r.db.Where("receipt = ?", receipt).Find(&pending).Error; err != nil {
+			return nil, false, err
+		}
+		if len(pending) == 0 {
+			return nil, false, appError.ErrNotFound
 		}
 
-		surveys = append(surveys, *survey)
+		return &model.BallotLogEntry{SurveyRefer: pending[0].SurveyRefer, Receipt: pending[0].Receipt}, false, nil
 	}
 
-	return surveys, nil
+	var count int64
+	if err = r.db.Model(&model.BallotLogEntry{}).Where("replaces = ?", receipt).Count(&count).Error; err != nil {
+		return nil, false, err
+	}
+
+	return &entries[0], count > 0, nil
 }
 
-// FindByID implements the method to find a survey from the store
-func (r *SurveyRepository) ListActive(limit, offset uint) (surveys []model.Survey, err error) {
-	rows, err := r.db.Raw("SELECT s.id, s.user_refer, s.subject, s.description, s.date_start, s.date_end FROM `surveys` AS s WHERE `s`.`deleted_at` IS NULL AND `s`.`confirm_status` = 'confirmed' AND date('now') BETWEEN `s`.`date_start` AND `s`.`date_end` ORDER BY `s`.`id` LIMIT ? OFFSET ?", limit, offset).Rows()
+// ListUnpublishedBallotRoots implements the method to list closed surveys whose ballot root is not published
+func (r *SurveyRepository) ListUnpublishedBallotRoots() (surveyIDs []uint, err error) {
+	where, args := statusFilter(model.SurveyStatusClosed, time.Now().UTC())
+	rows, err := r.db.Raw("SELECT s.id FROM `surveys` AS s WHERE `s`.`deleted_at` IS NULL AND (s.ballot_root IS NULL OR s.ballot_root = '') AND "+where+" ORDER BY `s`.`id`", args...).Rows()
 	if err != nil {
 		return nil, err
 	}
 
 	defer rows.Close()
-	// Values to load into
-	surveys = make([]model.Survey, 0)
+	surveyIDs = make([]uint, 0)
 
 	for rows.Next() {
-		survey := &model.Survey{}
-
-		err = rows.Scan(&survey.ID, &survey.UserRefer, &survey.Subject, &survey.Description, &survey.DateStart, &survey.DateEnd)
-		if err != nil {
+		var id uint
+		if err = rows.Scan(&id); err != nil {
 			return nil, err
 		}
-
-		surveys = append(surveys, *survey)
+		surveyIDs = append(surveyIDs, id)
 	}
 
-	return surveys, nil
+	return surveyIDs, nil
 }
 
-// FindByID implements the method to find a survey from the store
-func (r *SurveyRepository) ListResults(limit, offset uint) (surveys []model.Survey, err error) {
-	// Query with joins
-	rows, err := r.db.Raw("SELECT s.id, s.user_refer, s.subject, s.description, s.date_start, s.date_end, q.id AS question_id, q.value AS question_value, c.id AS choice_id, c.value AS choice_value, v.id AS vote_id FROM (SELECT * FROM `surveys` WHERE `surveys`.`deleted_at` IS NULL AND `surveys`.`confirm_status` = 'confirmed' AND NOT date('now') BETWEEN `surveys`.`date_start` AND `surveys`.`date_end` ORDER BY `surveys`.`id` LIMIT ? OFFSET ?) AS s JOIN questions AS q ON q.survey_refer = s.id JOIN choices AS c ON c.question_refer = q.id LEFT JOIN votes AS v ON v.choice_refer = c.id ORDER BY c.id", limit, offset).Rows()
-	if err != nil {
-		return nil, err
-	}
+// PublishBallotRoot implements the method to record the root of a closed survey once
+func (r *SurveyRepository) PublishBallotRoot(surveyID uint, root string) error {
+	return r.db.Model(&model.Survey{}).Where("id = ? AND (ballot_root IS NULL OR ballot_root = '')", surveyID).Update("ballot_root", root).Error
+}
 
-	defer rows.Close()
-	// Values to load into
-	surveys = make([]model.Survey, 0)
+// SetPreviousWave implements the method to link a survey to the earlier wave of its series, nil unlinks it
+func (r *SurveyRepository) SetPreviousWave(surveyID uint, previousID *uint) error {
+	return r.db.Model(&model.Survey{}).Where("id = ?", surveyID).Update("previous_wave", previousID).Error
+}
 
-	for rows.Next() {
-		survey := &model.Survey{}
-		survey.Questions = make([]model.Question, 0)
+// ballotSurvey loads a survey that is open for voting and checks the answers against it
+func (r *SurveyRepository) ballotSurvey(surveyID uint, answers []model.Answer, now time.Time) (reduced, survey *model.Survey, err error) {
+	reduced, err = r.FindByIDReduced(surveyID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if reduced.ID == 0 {
+		return nil, nil, appError.ErrNotFound
+	}
+	if reduced.Status(now) != model.SurveyStatusActive {
+		return nil, nil, errors.New("survey must be active before you can vote")
+	}
 
-		question := &model.Question{}
-		question.Choices = make([]model.Choice, 0)
+	survey, err = r.FindByIDWithoutVotes(surveyID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = survey.ValidateAnswers(answers); err != nil {
+		return nil, nil, err
+	}
 
-		choice := &model.Choice{}
-		choice.Votes = make([]model.Vote, 0)
+	return reduced, survey, nil
+}
 
-		var voteID sql.NullInt64
+// redeemInvitation takes one use of the invitation to a private survey for the first
+// ballot of the user
+func redeemInvitation(tx *gorm.DB, surveyID, userID uint, token string, now time.Time) error {
+	result := tx.Model(&model.Invitation{}).Where("survey_refer = ? AND token = ? AND revoked_at IS NULL AND (max_uses = 0 OR uses < max_uses)", surveyID, token).
+		UpdateColumn("uses", gorm.Expr("uses + 1"))
+	if err := result.Error; err != nil {
+		return err
+	}
+	if result.RowsAffected == 0 {
+		return appError.ErrInvalidInvitation
+	}
 
-		err = rows.Scan(&survey.ID, &survey.UserRefer, &survey.Subject, &survey.Description, &survey.DateStart, &survey.DateEnd,
-			&question.ID, &question.Value, &choice.ID, &choice.Value, &voteID)
-		if err != nil {
-			return nil, err
-		}
+	invitation := &model.Invitation{}
+	if err := tx.Where("token = ?", token).First(invitation).Error; err != nil {
+		return err
+	}
 
-		// Check if surveys exists in result array
-		isNewSurvey := true
-		for i, s := range surveys {
-			if s.ID == survey.ID {
-				survey = &surveys[i]
-				isNewSurvey = false
-			}
-		}
+	return tx.Create(&model.Redemption{InvitationRefer: invitation.ID, UserRefer: userID, CreatedAt: now}).Error
+}
 
-		// Check if question exists in survey
-		isNewQuestion := true
-		for i, s := range survey.Questions {
-			if s.ID == question.ID {
-				question = &survey.Questions[i]
-				isNewQuestion = false
-			}
-		}
+// CreateInvitations implements the method to persist a batch of invitations
+func (r *SurveyRepository) CreateInvitations(invitations []model.Invitation) error {
+	return r.db.Create(&invitations).Error
+}
 
-		// Check if choice exists in
-		isNewChoice := true
-		for i, s := range question.Choices {
-			if s.ID == choice.ID {
-				choice = &question.Choices[i]
-				isNewChoice = false
-			}
-		}
+// ListInvitations implements the method to list the invitations of a survey with their uses
+func (r *SurveyRepository) ListInvitations(surveyID uint) (invitations []model.Invitation, err error) {
+	invitations = make([]model.Invitation, 0)
 
-		if !voteID.Valid {
-			// vote id is null meaning this choice does not have any votes yet
-			// if this choice is added to results already we can ignore this vote
-			// but if this choice is not added to results we will add with empty vote array
-			if isNewChoice {
-				// vote is null but choice is new so lets add this
-				question.Choices = append(question.Choices, *choice)
-			} // else vote is null and choice is already added so we can ignore this
+	result := r.db.Where("survey_refer = ?", surveyID).Order("id").Find(&invitations)
 
-			if isNewQuestion {
-				survey.Questions = append(survey.Questions, *question)
-			}
+	if err = result.Error; err != nil {
+		return nil, err
+	}
 
-			if isNewSurvey {
-				surveys = append(surveys, *survey)
-			}
-		} else { // vote is not null
-			voteID := uint(voteID.Int64)
-			vote := model.Vote{}
-			vote.ID = voteID
-			vote.Model.ID = voteID
-
-			isNewVote := true
-			for _, s := range choice.Votes {
-				if s.ID == voteID {
-					isNewVote = false
-				}
-			}
+	return invitations, nil
+}
 
-			if !isNewVote { // should not be possible, panic to see if it occurs
-				r.logger.DPanic("DUBLICATE VOTE")
-				continue
-			}
+// RevokeInvitation implements the method to stop an invitation from admitting more voters
+func (r *SurveyRepository) RevokeInvitation(surveyID, invitationID uint, now time.Time) error {
+	result := r.db.Model(&model.Invitation{}).Where("id = ? AND survey_refer = ?", invitationID, surveyID).Update("revoked_at", now)
+	if err := result.Error; err != nil {
+		return err
+	}
+	if result.RowsAffected == 0 {
+		return appError.ErrNotFound
+	}
 
-			choice.Votes = append(choice.Votes, vote)
+	return nil
+}
 
-			if isNewChoice {
-				question.Choices = append(question.Choices, *choice)
-			}
+// deleteDraft removes the draft of a ballot once it is cast
+func deleteDraft(tx *gorm.DB, userID, surveyID uint) error {
+	return tx.Where("user_refer = ? AND survey_refer = ?", userID, surveyID).Delete(&model.DraftBallot{}).Error
+}
 
-			if isNewQuestion {
-				survey.Questions = append(survey.Questions, *question)
-			}
+// SaveDraft implements the method to create or overwrite the draft ballot of a user
+func (r *SurveyRepository) SaveDraft(draft *model.DraftBallot) error {
+	return r.db.Clauses(clause.OnConflict{UpdateAll: true}).Create(draft).Error
+}
 
-			if isNewSurvey {
-				surveys = append(surveys, *survey)
-			}
-		}
+// FindDraft implements the method to find the draft ballot of a user
+func (r *SurveyRepository) FindDraft(userID, surveyID uint) (draft *model.DraftBallot, err error) {
+	drafts := make([]model.DraftBallot, 0)
+	if err = r.db.Where("user_refer = ? AND survey_refer = ?", userID, surveyID).Find(&drafts).Error; err != nil {
+		return nil, err
+	}
+	if len(drafts) == 0 {
+		return nil, appError.ErrNotFound
 	}
 
-	return surveys, nil
-}
-
-// FindByID implements the method to find a survey from the store
-func (r *SurveyRepository) FindByIDReduced(id uint) (survey *model.Survey, err error) {
-	// Query with joins
-	rows, err := r.db.Raw("SELECT s.id, s.user_refer, s.subject, s.description, s.date_start, s.date_end FROM `surveys` AS s WHERE `s`.`id` = ?", id).Rows()
-	if err != nil {
+	draft = &drafts[0]
+	if err = draft.Decode(); err != nil {
 		return nil, err
 	}
 
-	defer rows.Close()
-	// Values to load into
-	survey = &model.Survey{}
+	return draft, nil
+}
 
-	for rows.Next() {
-		err = rows.Scan(&survey.ID, &survey.UserRefer, &survey.Subject, &survey.Description, &survey.DateStart, &survey.DateEnd)
-		if err != nil {
-			return nil, err
-		}
+// DeleteClosedDrafts implements the method to delete the drafts of surveys that no longer accept ballots
+func (r *SurveyRepository) DeleteClosedDrafts(now time.Time) (deleted int64, err error) {
+	closed := r.db.Unscoped().Model(&model.Survey{}).Select("id").Where("date_end < ? OR confirm_status <> ? OR deleted_at IS NOT NULL", now, model.ConfirmStatusAccepted)
+	result := r.db.Where("survey_refer IN (?)", closed).Delete(&model.DraftBallot{})
+	if err = result.Error; err != nil {
+		return 0, err
 	}
 
-	return survey, nil
+	return result.RowsAffected, nil
 }
 
-// FindByID implements the method to find a survey from the store
-func (r *SurveyRepository) FindByIDWithVotes(id uint) (survey *model.Survey, err error) {
-	// Query with joins
-	rows, err := r.db.Raw("SELECT s.id, s.user_refer, s.subject, s.description, s.date_start, s.date_end, q.id AS question_id, q.value AS question_value, c.id AS choice_id, c.value AS choice_value, v.id AS vote_id FROM (SELECT * FROM `surveys` WHERE `surveys`.`id` = ?) AS s JOIN questions AS q ON q.survey_refer = s.id JOIN choices AS c ON c.question_refer = q.id LEFT JOIN votes AS v ON v.choice_refer = c.id ORDER BY c.id", id).Rows()
+// CreateTemplate implements the method to store a survey template
+func (r *SurveyRepository) CreateTemplate(template *model.SurveyTemplate) error {
+	return r.db.Create(template).Error
+}
+
+// ListTemplates implements the method to list the organization templates and the
+// personal templates of the user, without their definitions
+func (r *SurveyRepository) ListTemplates(userID uint) (templates []model.SurveyTemplate, err error) {
+	templates = make([]model.SurveyTemplate, 0)
+	err = r.db.Select("id", "created_at", "updated_at", "name", "user_refer", "published_by", "organization").
+		Where("organization OR user_refer = ?", userID).
+		Order("organization DESC, name, id").
+		Find(&templates).Error
 	if err != nil {
 		return nil, err
 	}
 
-	defer rows.Close()
+	return templates, nil
+}
+
+// FindTemplate implements the method to find a survey template with its definition
+func (r *SurveyRepository) FindTemplate(id uint) (template *model.SurveyTemplate, err error) {
+	templates := make([]model.SurveyTemplate, 0)
+	if err = r.db.Where("id = ?", id).Find(&templates).Error; err != nil {
+		return nil, err
+	}
+	if len(templates) == 0 {
+		return nil, appError.ErrNotFound
+	}
+
+	template = &templates[0]
+	if err = template.Decode(); err != nil {
+		return nil, err
+	}
+
+	return template, nil
+}
+
+// DeleteTemplate implements the method to remove a survey template
+func (r *SurveyRepository) DeleteTemplate(id uint) error {
+	return r.db.Delete(&model.SurveyTemplate{}, id).Error
+}
+
+// fillQuotas counts the first ballot of a user into the quotas of the survey, a full
+// quota rejects the ballot. The survey closes once every quota is met
+func fillQuotas(tx *gorm.DB, survey *model.Survey, userID uint, now time.Time) error {
+	result := tx.Exec("UPDATE `surveys` SET respondents = respondents + 1 WHERE id = ? AND (respondent_cap = 0 OR respondents < respondent_cap)", survey.ID)
+	if err := result.Error; err != nil {
+		return err
+	}
+	if result.RowsAffected == 0 {
+		return appError.ErrQuotaFull
+	}
+
+	quotas := make([]model.Quota, 0)
+	if err := tx.Where("survey_refer = ?", survey.ID).Order("id").Find(&quotas).Error; err != nil {
+		return err
+	}
+	if len(quotas) > 0 {
+		user := &model.User{}
+		if err := tx.First(user, userID).Error; err != nil {
+			return err
+		}
+
+		for i := range quotas {
+			if !quotas[i].Matches(user, now) {
+				continue
+			}
+
+			result := tx.Model(&model.Quota{}).Where("id = ? AND filled < target", quotas[i].ID).UpdateColumn("filled", gorm.Expr("filled + 1"))
+			if err := result.Error; err != nil {
+				return err
+			}
+			if result.RowsAffected == 0 {
+				return appError.ErrQuotaFull
+			}
+			quotas[i].Filled++
+		}
+	}
+
+	if !model.QuotasMet(survey.RespondentCap, survey.Respondents+1, quotas) {
+		return nil
+	}
+
+	return tx.Model(&model.Survey{}).Where("id = ?", survey.ID).Updates(map[string]interface{}{"date_end": now, "quotas_met_at": now}).Error
+}
+
+// ListQuotas implements the method to list the quotas of a survey with their fills
+func (r *SurveyRepository) ListQuotas(surveyID uint) (quotas []model.Quota, err error) {
+	quotas = make([]model.Quota, 0)
+
+	result := r.db.Where("survey_refer = ?", surveyID).Order("id").Find(&quotas)
+
+	if err = result.Error; err != nil {
+		return nil, err
+	}
+
+	return quotas, nil
+}
+
+// addDemographicTallies adds the votes of an anonymous ballot to the demographic tallies
+func addDemographicTallies(tx *gorm.DB, tallies []model.DemographicTally) error {
+	for _, tally := range tallies {
+		err := tx.Exec("INSERT INTO `demographic_tallies` (question_refer, choice_refer, dimension, grp, count) VALUES (?, ?, ?, ?, ?) "+
+			"ON CONFLICT (question_refer, choice_refer, dimension, grp) DO UPDATE SET count = demographic_tallies.count + excluded.count",
+			tally.QuestionRefer, tally.ChoiceRefer, tally.Dimension, tally.Group, tally.Count).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// replaceBallot moves the current ballot of the user to the history, takes its votes
+// off the tallies and records the responses of the new ballot
+func (r *SurveyRepository) replaceBallot(userID, surveyID uint, responses []model.Response) (replaces string, err error) {
+	votes, err := r.ListBallotVotes(userID, surveyID)
+	if err != nil {
+		return "", err
+	}
+
+	revision, err := r.CountReplacedBallots(userID, surveyID)
+	if err != nil {
+		return "", err
+	}
+
+	if len(votes) > 0 {
+		replaced := make([]model.ReplacedVote, 0)
+		ids := make([]uint, 0)
+		for _, vote := range votes {
+			replaced = append(replaced, model.NewReplacedVote(vote, surveyID, revision+1))
+			ids = append(ids, vote.ID)
+		}
+		if err = r.db.Create(&replaced).Error; err != nil {
+			return "", err
+		}
+		// the unique vote index covers deleted votes too
+		if err = r.db.Unscoped().Delete(&model.Vote{}, ids).Error; err != nil {
+			return "", err
+		}
+		if err = subtractTallies(r.db.DB, model.NewTallies(votes)); err != nil {
+			return "", err
+		}
+	}
+
+	questions := r.db.Model(&model.Question{}).Select("id").Where("survey_refer = ?", surveyID)
+	if err = r.db.Unscoped().Where("user_refer = ? AND question_refer IN (?)", userID, questions).Delete(&model.Response{}).Error; err != nil {
+		return "", err
+	}
+	if len(responses) > 0 {
+		if err = r.db.Create(&responses).Error; err != nil {
+			return "", err
+		}
+	}
+
+	// ballots cast before the ballot log have no receipt
+	if len(votes) > 0 {
+		replaces = votes[0].BallotKey
+	}
+
+	return replaces, nil
+}
+
+// ListBallotVotes implements the method to list the current votes of a user in a survey
+func (r *SurveyRepository) ListBallotVotes(userID, surveyID uint) (votes []model.Vote, err error) {
+	votes = make([]model.Vote, 0)
+	err = r.db.Joins("JOIN questions AS q ON q.id = votes.question_refer").
+		Where("q.survey_refer = ? AND votes.user_refer = ?", surveyID, userID).
+		Order("votes.question_refer, votes.rank, votes.id").Find(&votes).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return votes, nil
+}
+
+// CountReplacedBallots implements the method to count how often a user changed a ballot
+func (r *SurveyRepository) CountReplacedBallots(userID, surveyID uint) (count uint, err error) {
+	rows, err := r.db.Raw("SELECT COALESCE(MAX(revision), 0) FROM `replaced_votes` WHERE user_refer = ? AND survey_refer = ? AND deleted_at IS NULL", userID, surveyID).Rows()
+	if err != nil {
+		return 0, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		if err = rows.Scan(&count); err != nil {
+			return 0, err
+		}
+	}
+
+	return count, nil
+}
+
+// withTx returns a repository running its queries in the transaction
+func (r *SurveyRepository) withTx(tx *gorm.DB) *SurveyRepository {
+	return &SurveyRepository{db: &storage.DbStore{DB: tx}, logger: r.logger}
+}
+
+// addTallies adds the counts of new votes to the stored tallies
+func addTallies(tx *gorm.DB, tallies []model.Tally) error {
+	for _, tally := range tallies {
+		err := tx.Exec("INSERT INTO `tallies` (question_refer, choice_refer, count, respondents, rank_sum, number_sum, number_min, number_max) VALUES (?, ?, ?, ?, ?, ?, ?, ?) "+
+			"ON CONFLICT (question_refer, choice_refer) DO UPDATE SET count = tallies.count + excluded.count, respondents = tallies.respondents + excluded.respondents, "+
+			"rank_sum = tallies.rank_sum + excluded.rank_sum, number_sum = tallies.number_sum + excluded.number_sum, "+
+			"number_min = MIN(COALESCE(tallies.number_min, excluded.number_min), COALESCE(excluded.number_min, tallies.number_min)), "+
+			"number_max = MAX(COALESCE(tallies.number_max, excluded.number_max), COALESCE(excluded.number_max, tallies.number_max))",
+			tally.QuestionRefer, tally.ChoiceRefer, tally.Count, tally.Respondents, tally.RankSum, tally.NumberSum, tally.NumberMin, tally.NumberMax).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// subtractTallies takes the counts of removed votes off the stored tallies, the
+// numeric bounds of the affected questions are recomputed from the remaining votes
+func subtractTallies(tx *gorm.DB, tallies []model.Tally) error {
+	for i := range tallies {
+		tally := &tallies[i]
+		tally.Count, tally.Respondents, tally.RankSum, tally.NumberSum = -tally.Count, -tally.Respondents, -tally.RankSum, -tally.NumberSum
+		numeric := tally.NumberMin != nil
+		tally.NumberMin, tally.NumberMax = nil, nil
+
+		if err := addTallies(tx, []model.Tally{*tally}); err != nil {
+			return err
+		}
+		if !numeric {
+			continue
+		}
+
+		err := tx.Exec("UPDATE `tallies` SET number_min = (SELECT MIN(v.number) FROM votes AS v WHERE v.question_refer = ? AND v.deleted_at IS NULL), "+
+			"number_max = (SELECT MAX(v.number) FROM votes AS v WHERE v.question_refer = ? AND v.deleted_at IS NULL) WHERE question_refer = ? AND choice_refer = 0",
+			tally.QuestionRefer, tally.QuestionRefer, tally.QuestionRefer).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ReconcileTallies implements the method to recount the tallies from the votes,
+// drifted tallies are overwritten with the recounted ones and reported
+func (r *SurveyRepository) ReconcileTallies() (drift []model.TallyDrift, err error) {
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		stored := make([]model.Tally, 0)
+		if err := tx.Find(&stored).Error; err != nil {
+			return err
+		}
+
+		counted, err := countTallies(tx)
+		if err != nil {
+			return err
+		}
+
+		drift = model.DiffTallies(stored, counted)
+		for _, d := range drift {
+			if err := tx.Save(&d.Counted).Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return drift, nil
+}
+
+// countTallies computes the tallies from the votes
+func countTallies(tx *gorm.DB) (tallies []model.Tally, err error) {
+	rows, err := tx.Raw("SELECT v.question_refer, v.choice_refer, COUNT(*), 0, SUM(v.rank), 0, NULL, NULL FROM `votes` AS v WHERE `v`.`deleted_at` IS NULL AND v.choice_refer IS NOT NULL GROUP BY v.question_refer, v.choice_refer " +
+		"UNION ALL SELECT v.question_refer, 0, COUNT(CASE WHEN v.choice_refer IS NULL THEN 1 END), COUNT(DISTINCT COALESCE(v.user_refer, v.ballot_key)), 0, COALESCE(SUM(v.number), 0), MIN(v.number), MAX(v.number) FROM `votes` AS v WHERE `v`.`deleted_at` IS NULL GROUP BY v.question_refer").Rows()
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	tallies = make([]model.Tally, 0)
+
+	for rows.Next() {
+		tally := model.Tally{}
+		err = rows.Scan(&tally.QuestionRefer, &tally.ChoiceRefer, &tally.Count, &tally.Respondents, &tally.RankSum, &tally.NumberSum, &tally.NumberMin, &tally.NumberMax)
+		if err != nil {
+			return nil, err
+		}
+
+		tallies = append(tallies, tally)
+	}
+
+	return tallies, nil
+}
+
+// FindByID implements the method to find a survey from the store
+func (r *SurveyRepository) VotedAlready(userID, surveyID uint) (voted bool, err error) {
+	// anonymous ballots are only known by the participation of the user
+	rows, err := r.db.Raw("SELECT v.id AS vote_id FROM (SELECT * FROM `surveys` WHERE `surveys`.`id` = ?) AS s JOIN questions AS q ON q.survey_refer = s.id LEFT JOIN choices AS c ON c.question_refer = q.id "+voteJoin+" WHERE v.user_refer = ? "+
+		"UNION ALL SELECT 0 FROM `participations` AS p WHERE p.survey_refer = ? AND p.user_refer = ?", surveyID, userID, surveyID, userID).Rows()
+	if err != nil {
+		return true, err
+	}
+
+	defer rows.Close()
+	voted = false
+
+	for rows.Next() {
+		voted = true
+		break
+	}
+
+	return voted, nil
+}
+
+// FindByID implements the method to find a survey from the store
+func (r *SurveyRepository) CountWaitingConfirmation() (count int, err error) {
+	where, args := statusFilter(model.SurveyStatusWaiting, time.Now().UTC())
+	rows, err := r.db.Raw("SELECT count(1) FROM `surveys` AS s WHERE `s`.`deleted_at` IS NULL AND "+where, args...).Rows()
+	if err != nil {
+		return -1, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		err = rows.Scan(&count)
+		if err != nil {
+			return -1, err
+		}
+	}
+
+	return count, nil
+}
+
+// SummarizeWaitingConfirmation implements the method to count the waiting, claimed and
+// overdue surveys of the review queue
+func (r *SurveyRepository) SummarizeWaitingConfirmation(overdueBefore time.Time) (queue *model.ReviewQueue, err error) {
+	now := time.Now().UTC()
+	where, args := statusFilter(model.SurveyStatusWaiting, now)
+	args = append([]interface{}{now, overdueBefore}, args...)
+	rows, err := r.db.Raw("SELECT count(1), "+
+		"count(CASE WHEN `s`.`claimed_until` > ? THEN 1 END), "+
+		"count(CASE WHEN COALESCE(`s`.`submitted_at`, `s`.`created_at`) < ? THEN 1 END) "+
+		"FROM `surveys` AS s WHERE `s`.`deleted_at` IS NULL AND "+where, args...).Rows()
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	queue = &model.ReviewQueue{}
+
+	for rows.Next() {
+		err = rows.Scan(&queue.Waiting, &queue.Claimed, &queue.Overdue)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// separate query, sqlite loses the column type of aggregated times
+	oldest := &model.Survey{}
+	result := r.db.Raw("SELECT s.created_at, s.submitted_at FROM `surveys` AS s WHERE `s`.`deleted_at` IS NULL AND "+where+
+		" ORDER BY COALESCE(`s`.`submitted_at`, `s`.`created_at`) LIMIT 1", args[2:]...).Row()
+	if err = result.Scan(&oldest.CreatedAt, &oldest.SubmittedAt); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if err == nil {
+		queue.OldestSubmittedAt = oldest.SubmittedAt
+		if queue.OldestSubmittedAt == nil {
+			queue.OldestSubmittedAt = &oldest.CreatedAt
+		}
+	}
+
+	return queue, nil
+}
+
+// FindByID implements the method to find a survey from the store
+func (r *SurveyRepository) CountActive(voter *model.User) (count int, err error) {
+	now := time.Now().UTC()
+	where, args := statusFilter(model.SurveyStatusActive, now)
+	where += " AND " + publicFilter
+	if voter != nil {
+		voterWhere, voterArgs := voterFilter(voter, now)
+		where, args = where+" AND "+voterWhere, append(args, voterArgs...)
+	}
+	rows, err := r.db.Raw("SELECT count(1) FROM `surveys` AS s WHERE `s`.`deleted_at` IS NULL AND "+where, args...).Rows()
+	if err != nil {
+		return -1, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		err = rows.Scan(&count)
+		if err != nil {
+			return -1, err
+		}
+	}
+
+	return count, nil
+}
+
+// FindByID implements the method to find a survey from the store
+func (r *SurveyRepository) CountResults() (count int, err error) {
+	where, args := statusFilter(model.SurveyStatusClosed, time.Now().UTC())
+	where += " AND " + publicFilter
+	rows, err := r.db.Raw("SELECT count(1) FROM `surveys` AS s WHERE `s`.`deleted_at` IS NULL AND "+where, args...).Rows()
+	if err != nil {
+		return -1, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		err = rows.Scan(&count)
+		if err != nil {
+			return -1, err
+		}
+	}
+
+	return count, nil
+}
+
+// FindByID implements the method to find a survey from the store
+func (r *SurveyRepository) ListWaitingConfirmation(reviewerID, limit, offset uint) (surveys []model.Survey, err error) {
+	where, args := statusFilter(model.SurveyStatusWaiting, time.Now().UTC())
+	// hide surveys the reviewer already decided on in this round
+	// and surveys claimed by another reviewer, the oldest submissions come first
+	now := time.Now().UTC()
+	args = append([]interface{}{model.ConfirmStatusAccepted, model.ConfirmStatusAccepted}, append(args, reviewerID, reviewerID, now, limit, offset)...)
+	rows, err := r.db.Raw("SELECT s.id, s.created_at, s.user_refer, s.subject, s.description, s.date_start, s.date_end, s.sensitive, s.audience, s.review_round, s.submitted_at, s.claimed_by, s.claimed_until, "+
+		"(SELECT count(1) FROM `moderations` AS m WHERE m.survey_refer = s.id AND m.round = s.review_round AND m.decision = ? AND m.deleted_at IS NULL) AS approvals, "+
+		"EXISTS (SELECT 1 FROM `moderations` AS m WHERE m.survey_refer = s.id AND m.round = s.review_round AND m.decision = ? AND m.senior AND m.deleted_at IS NULL) AS senior_approved "+
+		"FROM `surveys` AS s WHERE `s`.`deleted_at` IS NULL AND "+where+
+		" AND NOT EXISTS (SELECT 1 FROM `moderations` AS m WHERE m.survey_refer = s.id AND m.round = s.review_round AND m.reviewer_refer = ? AND m.deleted_at IS NULL)"+
+		" AND (s.claimed_by IS NULL OR s.claimed_by = ? OR s.claimed_until <= ?) ORDER BY COALESCE(`s`.`submitted_at`, `s`.`created_at`), `s`.`id` LIMIT ? OFFSET ?", args...).Rows()
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	// Values to load into
+	surveys = make([]model.Survey, 0)
+
+	for rows.Next() {
+		survey := &model.Survey{}
+		survey.Review = &model.Review{}
+
+		err = rows.Scan(&survey.ID, &survey.CreatedAt, &survey.UserRefer, &survey.Subject, &survey.Description, &survey.DateStart, &survey.DateEnd, &survey.Sensitive, &survey.Audience, &survey.ReviewRound,
+			&survey.SubmittedAt, &survey.ClaimedBy, &survey.ClaimedUntil, &survey.Review.Approvals, &survey.Review.SeniorApproved)
+		if err != nil {
+			return nil, err
+		}
+		if survey.SubmittedAt == nil {
+			// submitted before submission times were recorded
+			survey.SubmittedAt = &survey.CreatedAt
+		}
+
+		surveys = append(surveys, *survey)
+	}
+
+	return surveys, nil
+}
+
+// FindByID implements the method to find a survey from the store
+func (r *SurveyRepository) ListActive(limit, offset uint, voter *model.User) (surveys []model.Survey, err error) {
+	now := time.Now().UTC()
+	where, args := statusFilter(model.SurveyStatusActive, now)
+	where += " AND " + publicFilter
+	if voter != nil {
+		voterWhere, voterArgs := voterFilter(voter, now)
+		where, args = where+" AND "+voterWhere, append(args, voterArgs...)
+	}
+	rows, err := r.db.Raw("SELECT s.id, s.user_refer, s.subject, s.description, s.date_start, s.date_end FROM `surveys` AS s WHERE `s`.`deleted_at` IS NULL AND "+where+" ORDER BY `s`.`id` LIMIT ? OFFSET ?", append(args, limit, offset)...).Rows()
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	// Values to load into
+	surveys = make([]model.Survey, 0)
+
+	for rows.Next() {
+		survey := &model.Survey{}
+
+		err = rows.Scan(&survey.ID, &survey.UserRefer, &survey.Subject, &survey.Description, &survey.DateStart, &survey.DateEnd)
+		if err != nil {
+			return nil, err
+		}
+
+		surveys = append(surveys, *survey)
+	}
+
+	return surveys, nil
+}
+
+// ListResults implements the method to list finished surveys with their vote counts
+func (r *SurveyRepository) ListResults(limit, offset uint) (surveys []model.Survey, err error) {
+	// Query with joins, votes are read from the tallies
+	where, args := statusFilter(model.SurveyStatusClosed, time.Now().UTC())
+	where += " AND " + publicFilter
+	rows, err := r.db.Raw("SELECT s.id, s.user_refer, s.subject, s.description, s.date_start, s.date_end, s.sensitive, s.noise, s.privacy_budget, s.epsilon, "+questionColumns+", c.id AS choice_id, c.value AS choice_value, "+resultColumns+" FROM (SELECT * FROM `surveys` AS s WHERE `s`.`deleted_at` IS NULL AND "+where+" ORDER BY `s`.`id` LIMIT ? OFFSET ?) AS s JOIN questions AS q ON q.survey_refer = s.id LEFT JOIN choices AS c ON c.question_refer = q.id "+tallyJoin+" ORDER BY s.id, q.id, c.id", append(args, limit, offset)...).Rows()
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	// Values to load into
+	surveys = make([]model.Survey, 0)
+
+	for rows.Next() {
+		survey := model.Survey{}
+		row := &surveyRow{}
+
+		err = rows.Scan(&survey.ID, &survey.UserRefer, &survey.Subject, &survey.Description, &survey.DateStart, &survey.DateEnd, &survey.Sensitive, &survey.Noise, &survey.PrivacyBudget, &survey.Epsilon,
+			&row.question.ID, &row.question.Value, &row.question.Type, &row.question.MinChoices, &row.question.MaxChoices, &row.question.MinNumber, &row.question.MaxNumber,
+			&row.choiceID, &row.choiceValue, &row.voteCount, &row.meanRank, &row.respondents, &row.numberMin, &row.numberMax, &row.numberMean)
+		if err != nil {
+			return nil, err
+		}
+
+		// rows come ordered by survey
+		if len(surveys) == 0 || surveys[len(surveys)-1].ID != survey.ID {
+			survey.Questions = make([]model.Question, 0)
+			surveys = append(surveys, survey)
+		}
+
+		appendResultRow(&surveys[len(surveys)-1], row)
+	}
+
+	for i := range surveys {
+		summarizeSurvey(&surveys[i])
+	}
+
+	return surveys, nil
+}
+
+// FindByID implements the method to find a survey from the store
+func (r *SurveyRepository) FindByIDReduced(id uint) (survey *model.Survey, err error) {
+	// Query with joins
+	rows, err := r.db.Raw("SELECT s.id, s.user_refer, s.subject, s.description, s.date_start, s.date_end, s.confirm_status, s.sensitive, s.audience, s.review_round, s.submitted_at, s.claimed_by, s.claimed_until, s.min_cell_size, s.noise, s.privacy_budget, s.epsilon, s.ballot_changes, s.anonymous, COALESCE(s.ballot_root, ''), s.residents_only, s.min_age, s.max_age, s.respondent_cap, s.respondents, s.quotas_met_at, s.private, s.previous_wave FROM `surveys` AS s WHERE `s`.`id` = ? AND `s`.`deleted_at` IS NULL", id).Rows()
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	// Values to load into
+	survey = &model.Survey{}
+
+	for rows.Next() {
+		err = rows.Scan(&survey.ID, &survey.UserRefer, &survey.Subject, &survey.Description, &survey.DateStart, &survey.DateEnd, &survey.ConfirmStatus, &survey.Sensitive, &survey.Audience, &survey.ReviewRound,
+			&survey.SubmittedAt, &survey.ClaimedBy, &survey.ClaimedUntil, &survey.MinCellSize, &survey.Noise, &survey.PrivacyBudget, &survey.Epsilon, &survey.BallotChanges, &survey.Anonymous, &survey.BallotRoot,
+			&survey.Eligibility.ResidentsOnly, &survey.Eligibility.MinAge, &survey.Eligibility.MaxAge, &survey.RespondentCap, &survey.Respondents, &survey.QuotasMetAt, &survey.Private, &survey.PreviousWave)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err = r.loadEligibility(survey); err != nil {
+		return nil, err
+	}
+
+	return survey, nil
+}
+
+// loadEligibility attaches the set valued eligibility criteria to the survey
+func (r *SurveyRepository) loadEligibility(survey *model.Survey) error {
+	if survey.ID == 0 {
+		return nil
+	}
+
+	values := make([]model.EligibleValue, 0)
+	if err := r.db.Where("survey_refer = ?", survey.ID).Order("attribute, value").Find(&values).Error; err != nil {
+		return err
+	}
+
+	for _, value := range values {
+		survey.Eligibility.AddValue(value)
+	}
+
+	return nil
+}
+
+// CountEligibleUsers implements the method to count the registered users the eligibility criteria admit
+func (r *SurveyRepository) CountEligibleUsers(eligibility *model.Eligibility, now time.Time) (count int, err error) {
+	where, args := eligibleUsersFilter(eligibility, now)
+	rows, err := r.db.Raw("SELECT count(1) FROM `users` AS u WHERE `u`.`deleted_at` IS NULL AND "+where, args...).Rows()
+	if err != nil {
+		return -1, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		err = rows.Scan(&count)
+		if err != nil {
+			return -1, err
+		}
+	}
+
+	return count, nil
+}
+
+// FindByIDWithResults implements the method to find a survey with its vote counts
+func (r *SurveyRepository) FindByIDWithResults(id uint) (survey *model.Survey, err error) {
+	// Query with joins, votes are read from the tallies
+	rows, err := r.db.Raw("SELECT s.id, s.user_refer, s.subject, s.description, s.date_start, s.date_end, s.sensitive, s.noise, s.privacy_budget, s.epsilon, "+questionColumns+", c.id AS choice_id, c.value AS choice_value, "+resultColumns+" FROM (SELECT * FROM `surveys` WHERE `surveys`.`id` = ?) AS s JOIN questions AS q ON q.survey_refer = s.id LEFT JOIN choices AS c ON c.question_refer = q.id "+tallyJoin+" ORDER BY q.id, c.id", id).Rows()
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
 	// Values to load into
 	survey = &model.Survey{}
 	survey.Questions = make([]model.Question, 0)
 
 	for rows.Next() {
-		question := &model.Question{}
-		question.Choices = make([]model.Choice, 0)
-
-		choice := &model.Choice{}
-		choice.Votes = make([]model.Vote, 0)
+		row := &surveyRow{}
 
-		var voteID sql.NullInt64
-
-		err = rows.Scan(&survey.ID, &survey.UserRefer, &survey.Subject, &survey.Description, &survey.DateStart, &survey.DateEnd,
-			&question.ID, &question.Value, &choice.ID, &choice.Value, &voteID)
+		err = rows.Scan(&survey.ID, &survey.UserRefer, &survey.Subject, &survey.Description, &survey.DateStart, &survey.DateEnd, &survey.Sensitive, &survey.Noise, &survey.PrivacyBudget, &survey.Epsilon,
+			&row.question.ID, &row.question.Value, &row.question.Type, &row.question.MinChoices, &row.question.MaxChoices, &row.question.MinNumber, &row.question.MaxNumber,
+			&row.choiceID, &row.choiceValue, &row.voteCount, &row.meanRank, &row.respondents, &row.numberMin, &row.numberMax, &row.numberMean)
 		if err != nil {
 			return nil, err
 		}
 
-		// Check if question exists in survey
-		isNewQuestion := true
-		for i, s := range survey.Questions {
-			if s.ID == question.ID {
-				question = &survey.Questions[i]
-				isNewQuestion = false
-			}
+		appendResultRow(survey, row)
+	}
+
+	if err = r.loadConditions(survey); err != nil {
+		return nil, err
+	}
+
+	summarizeSurvey(survey)
+
+	return survey, nil
+}
+
+// ListVotes implements the method to list the individual votes of a survey
+func (r *SurveyRepository) ListVotes(surveyID uint) (votes []model.Vote, err error) {
+	rows, err := r.db.Raw("SELECT v.id, v.question_refer, v.choice_refer, v.rank, v.number, v.text FROM `votes` AS v JOIN questions AS q ON q.id = v.question_refer WHERE `q`.`survey_refer` = ? AND `v`.`deleted_at` IS NULL ORDER BY v.question_refer, v.id", surveyID).Rows()
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	votes = make([]model.Vote, 0)
+
+	for rows.Next() {
+		vote := model.Vote{}
+		if err = rows.Scan(&vote.ID, &vote.QuestionRefer, &vote.ChoiceRefer, &vote.Rank, &vote.Number, &vote.Text); err != nil {
+			return nil, err
 		}
 
-		// Check if choice exists in
-		isNewChoice := true
-		for i, s := range question.Choices {
-			if s.ID == choice.ID {
-				choice = &question.Choices[i]
-				isNewChoice = false
-			}
+		votes = append(votes, vote)
+	}
+
+	return votes, nil
+}
+
+// StreamRespondents implements the method to read the demographics of every voter of a
+// survey one at a time, anonymous ballots carry no voter
+func (r *SurveyRepository) StreamRespondents(surveyID uint, fn func(user *model.User) error) error {
+	rows, err := r.db.Raw("SELECT u.birth_sex, u.gender_identity, u.birth_date, u.is_resident FROM users AS u WHERE u.id IN "+
+		"(SELECT v.user_refer FROM `votes` AS v JOIN questions AS q ON q.id = v.question_refer WHERE q.survey_refer = ? AND v.deleted_at IS NULL)", surveyID).Rows()
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		user := &model.User{}
+		if err = rows.Scan(&user.BirthSex, &user.GenderIdentity, &user.BirthDate, &user.IsResident); err != nil {
+			return err
+		}
+		if err = fn(user); err != nil {
+			return err
 		}
+	}
 
-		if !voteID.Valid {
-			// vote id is null meaning this choice does not have any votes yet
-			// if this choice is added to results already we can ignore this vote
-			// but if this choice is not added to results we will add with empty vote array
-			if isNewChoice {
-				// vote is null but choice is new so lets add this
-				question.Choices = append(question.Choices, *choice)
-			} // else vote is null and choice is already added so we can ignore this
+	return rows.Err()
+}
 
-			if isNewQuestion {
-				survey.Questions = append(survey.Questions, *question)
-			}
-		} else { // vote is not null
-			voteID := uint(voteID.Int64)
-			vote := model.Vote{}
-			vote.ID = voteID
-			vote.Model.ID = voteID
-
-			isNewVote := true
-			for _, s := range choice.Votes {
-				if s.ID == voteID {
-					isNewVote = false
-				}
-			}
+// StreamResponses implements the method to read the votes of a survey one at a time,
+// grouped by ballot so the caller never holds more than one respondent in memory
+func (r *SurveyRepository) StreamResponses(surveyID uint, fn func(row *model.ResponseRow) error) error {
+	rows, err := r.db.Raw("SELECT COALESCE(NULLIF(v.ballot_key, ''), CAST(v.user_refer AS TEXT)) AS respondent, v.question_refer, v.choice_refer, v.rank, v.number, v.text, "+
+		"u.id, u.birth_sex, u.gender_identity, u.birth_date, u.is_resident "+
+		"FROM `votes` AS v JOIN questions AS q ON q.id = v.question_refer LEFT JOIN users AS u ON u.id = v.user_refer "+
+		"WHERE q.survey_refer = ? AND v.deleted_at IS NULL AND q.deleted_at IS NULL ORDER BY respondent, v.question_refer, v.rank, v.choice_refer", surveyID).Rows()
+	if err != nil {
+		return err
+	}
 
-			if !isNewVote { // should not be possible, panic to see if it occurs
-				r.logger.DPanic("DUBLICATE VOTE")
-				continue
-			}
+	defer rows.Close()
+
+	for rows.Next() {
+		row := &model.ResponseRow{}
+		var userID sql.NullInt64
+		var birthSex, genderIdentity sql.NullString
+		var birthDate sql.NullTime
+		var isResident *bool
 
-			choice.Votes = append(choice.Votes, vote)
+		err = rows.Scan(&row.Respondent, &row.QuestionID, &row.ChoiceID, &row.Rank, &row.Number, &row.Text,
+			&userID, &birthSex, &genderIdentity, &birthDate, &isResident)
+		if err != nil {
+			return err
+		}
 
-			if isNewChoice {
-				question.Choices = append(question.Choices, *choice)
+		if userID.Valid {
+			row.User = &model.User{
+				BirthSex:       model.BirthSex(birthSex.String),
+				GenderIdentity: model.GenderIdentity(genderIdentity.String),
+				BirthDate:      birthDate.Time,
+				IsResident:     isResident,
 			}
+		}
 
-			if isNewQuestion {
-				survey.Questions = append(survey.Questions, *question)
-			}
+		if err = fn(row); err != nil {
+			return err
 		}
 	}
 
-	return survey, nil
+	return rows.Err()
 }
 
 // FindByID implements the method to find a survey from the store
 func (r *SurveyRepository) FindByIDWithoutVotes(id uint) (survey *model.Survey, err error) {
 	// Query with joins
-	rows, err := r.db.Raw("SELECT s.id, s.user_refer, s.subject, s.description, s.date_start, s.date_end, q.id AS question_id, q.value AS question_value, c.id AS choice_id, c.value AS choice_value FROM (SELECT * FROM `surveys` WHERE `surveys`.`id` = ?) AS s JOIN questions AS q ON q.survey_refer = s.id JOIN choices AS c ON c.question_refer = q.id ORDER BY c.id", id).Rows()
+	rows, err := r.db.Raw("SELECT s.id, s.user_refer, s.subject, s.description, s.date_start, s.date_end, "+questionColumns+", c.id AS choice_id, c.value AS choice_value FROM (SELECT * FROM `surveys` WHERE `surveys`.`id` = ?) AS s JOIN questions AS q ON q.survey_refer = s.id LEFT JOIN choices AS c ON c.question_refer = q.id ORDER BY q.id, c.id", id).Rows()
 	if err != nil {
 		return nil, err
 	}
@@ -540,50 +1434,55 @@ func (r *SurveyRepository) FindByIDWithoutVotes(id uint) (survey *model.Survey,
 	survey.Questions = make([]model.Question, 0)
 
 	for rows.Next() {
-		question := &model.Question{}
-		question.Choices = make([]model.Choice, 0)
-
-		choice := &model.Choice{}
-		choice.Votes = make([]model.Vote, 0)
+		row := &surveyRow{}
 
 		err = rows.Scan(&survey.ID, &survey.UserRefer, &survey.Subject, &survey.Description, &survey.DateStart, &survey.DateEnd,
-			&question.ID, &question.Value, &choice.ID, &choice.Value)
+			&row.question.ID, &row.question.Value, &row.question.Type, &row.question.MinChoices, &row.question.MaxChoices, &row.question.MinNumber, &row.question.MaxNumber,
+			&row.choiceID, &row.choiceValue)
 		if err != nil {
 			return nil, err
 		}
 
-		// Check if question exists in survey
-		isNewQuestion := true
-		for i, s := range survey.Questions {
-			if s.ID == question.ID {
-				question = &survey.Questions[i]
-				isNewQuestion = false
-			}
-		}
+		appendSurveyRow(survey, row)
+	}
 
-		// Check if choice exists in
-		isNewChoice := true
-		for i, s := range question.Choices {
-			if s.ID == choice.ID {
-				choice = &question.Choices[i]
-				isNewChoice = false
-			}
-		}
+	if err = r.loadConditions(survey); err != nil {
+		return nil, err
+	}
 
-		// vote id is null meaning this choice does not have any votes yet
-		// if this choice is added to results already we can ignore this vote
-		// but if this choice is not added to results we will add with empty vote array
-		if isNewChoice {
-			// vote is null but choice is new so lets add this
-			question.Choices = append(question.Choices, *choice)
-		} // else vote is null and choice is already added so we can ignore this
+	return survey, nil
+}
 
-		if isNewQuestion {
-			survey.Questions = append(survey.Questions, *question)
+// loadConditions attaches the display conditions to the loaded questions
+func (r *SurveyRepository) loadConditions(survey *model.Survey) error {
+	rows, err := r.db.Raw("SELECT cd.id, cd.question_refer, cd.source_index, cd.choice_index FROM `conditions` AS cd JOIN questions AS q ON q.id = cd.question_refer WHERE `q`.`survey_refer` = ? AND `cd`.`deleted_at` IS NULL ORDER BY cd.id", survey.ID).Rows()
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+
+	for i := range survey.Questions {
+		survey.Questions[i].Conditions = make([]model.Condition, 0)
+	}
+
+	for rows.Next() {
+		condition := model.Condition{}
+
+		err = rows.Scan(&condition.ID, &condition.QuestionRefer, &condition.SourceIndex, &condition.ChoiceIndex)
+		if err != nil {
+			return err
 		}
+
+		question := survey.Question(condition.QuestionRefer)
+		if question == nil {
+			continue
+		}
+
+		question.Conditions = append(question.Conditions, condition)
 	}
 
-	return survey, nil
+	return nil
 }
 
 // FindByID implements the method to find a survey from the store
@@ -646,13 +1545,437 @@ func (r *SurveyRepository) UpdateByID(id uint, surveyUpdate model.Survey) error
 	return nil
 }
 
+// ReplaceDraft implements the method to replace the fields and question tree of a survey
+// and return it to draft in the same transaction
+func (r *SurveyRepository) ReplaceDraft(id uint, from model.ConfirmStatus, surveyReplace *model.Survey, records ...interface{}) (_ *model.Survey, err error) {
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		if err := transition(tx, id, from, model.ConfirmStatusDraft, records...); err != nil {
+			return err
+		}
+
+		questionIDs := tx.Model(&model.Question{}).Select("id").Where("survey_refer = ?", id)
+
+		// questions of an unconfirmed survey have no votes, remove them for good
+		if err := tx.Unscoped().Where("question_refer IN (?)", questionIDs).Delete(&model.Condition{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("question_refer IN (?)", questionIDs).Delete(&model.Choice{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("survey_refer = ?", id).Delete(&model.Question{}).Error; err != nil {
+			return err
+		}
+
+		surveyReplace.ID = id
+		for i := range surveyReplace.Questions {
+			surveyReplace.Questions[i].SurveyRefer = id
+		}
+
+		result := tx.Model(&model.Survey{}).Where("id = ?", id).Updates(map[string]interface{}{
+			"subject":        surveyReplace.Subject,
+			"description":    surveyReplace.Description,
+			"date_start":     surveyReplace.DateStart,
+			"date_end":       surveyReplace.DateEnd,
+			"confirm_status": surveyReplace.ConfirmStatus,
+			"sensitive":      surveyReplace.Sensitive,
+			"audience":       surveyReplace.Audience,
+			"min_cell_size":  surveyReplace.MinCellSize,
+			"noise":          surveyReplace.Noise,
+			"privacy_budget": surveyReplace.PrivacyBudget,
+			"epsilon":        surveyReplace.Epsilon,
+			"ballot_changes": surveyReplace.BallotChanges,
+			"anonymous":      surveyReplace.Anonymous,
+			"residents_only": surveyReplace.Eligibility.ResidentsOnly,
+			"min_age":        surveyReplace.Eligibility.MinAge,
+			"max_age":        surveyReplace.Eligibility.MaxAge,
+			"respondent_cap": surveyReplace.RespondentCap,
+			"private":        surveyReplace.Private,
+		})
+		if err := result.Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("survey_refer = ?", id).Delete(&model.EligibleValue{}).Error; err != nil {
+			return err
+		}
+		if err := createEligibleValues(tx, surveyReplace.Eligibility.Values(id)); err != nil {
+			return err
+		}
+
+		// quotas of an unconfirmed survey are not filled yet
+		if err := tx.Unscoped().Where("survey_refer = ?", id).Delete(&model.Quota{}).Error; err != nil {
+			return err
+		}
+		if len(surveyReplace.Quotas) > 0 {
+			for i := range surveyReplace.Quotas {
+				surveyReplace.Quotas[i].SurveyRefer = id
+			}
+			if err := tx.Create(&surveyReplace.Quotas).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Omit("Votes", "Choices.Votes").Create(&surveyReplace.Questions).Error
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return surveyReplace, nil
+}
+
+// Transition implements the method to change the stored status of a survey and
+// create the records of the change in the same transaction
+func (r *SurveyRepository) Transition(id uint, from, to model.ConfirmStatus, records ...interface{}) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return transition(tx, id, from, to, records...)
+	})
+}
+
+// UpdateDraft implements the method to update the fields of a survey and return it to
+// draft in the same transaction
+func (r *SurveyRepository) UpdateDraft(id uint, from model.ConfirmStatus, surveyUpdate model.Survey, records ...interface{}) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := transition(tx, id, from, model.ConfirmStatusDraft, records...); err != nil {
+			return err
+		}
+
+		return tx.Model(&model.Survey{}).Where("id = ?", id).Updates(surveyUpdate).Error
+	})
+}
+
+// transition changes the stored status of a survey within the transaction
+func transition(tx *gorm.DB, id uint, from, to model.ConfirmStatus, records ...interface{}) error {
+	// claims only last while the survey is waiting
+	update := map[string]interface{}{"confirm_status": to, "claimed_by": nil, "claimed_until": nil}
+	if to == model.ConfirmStatusWaiting {
+		// every submission starts a new review round
+		update["review_round"] = gorm.Expr("review_round + 1")
+		update["submitted_at"] = time.Now().UTC()
+	}
+
+	// only update if nobody changed the status since it was read
+	result := tx.Model(&model.Survey{}).Where("id = ? AND confirm_status = ?", id, from).Updates(update)
+	if err := result.Error; err != nil {
+		return err
+	}
+	if result.RowsAffected == 0 {
+		return appError.ErrStatusChanged
+	}
+
+	for _, record := range records {
+		if err := tx.Create(record).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Claim implements the method to lock a waiting survey to a reviewer until the given time,
+// it fails if another reviewer holds an unexpired claim
+func (r *SurveyRepository) Claim(id, reviewerID uint, until time.Time) error {
+	result := r.db.Model(&model.Survey{}).
+		Where("id = ? AND confirm_status = ?", id, model.ConfirmStatusWaiting).
+		Where("claimed_by IS NULL OR claimed_by = ? OR claimed_until <= ?", reviewerID, time.Now().UTC()).
+		Updates(map[string]interface{}{"claimed_by": reviewerID, "claimed_until": until})
+
+	if err := result.Error; err != nil {
+		return err
+	}
+	if result.RowsAffected == 0 {
+		return appError.ErrSurveyClaimed
+	}
+
+	return nil
+}
+
+// Release implements the method to remove the claim of a reviewer from a survey
+func (r *SurveyRepository) Release(id, reviewerID uint) error {
+	result := r.db.Model(&model.Survey{}).
+		Where("id = ? AND claimed_by = ?", id, reviewerID).
+		Updates(map[string]interface{}{"claimed_by": nil, "claimed_until": nil})
+
+	if err := result.Error; err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SetSensitive implements the method to flag a waiting survey as a sensitive topic
+func (r *SurveyRepository) SetSensitive(id uint, sensitive bool) error {
+	result := r.db.Model(&model.Survey{}).
+		Where("id = ? AND confirm_status = ?", id, model.ConfirmStatusWaiting).
+		Update("sensitive", sensitive)
+
+	if err := result.Error; err != nil {
+		return err
+	}
+	if result.RowsAffected == 0 {
+		return appError.ErrStatusChanged
+	}
+
+	return nil
+}
+
+// CreateModeration implements the method to persist a review decision
+func (r *SurveyRepository) CreateModeration(moderation *model.Moderation) error {
+	result := r.db.Create(moderation)
+
+	if err := result.Error; err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ListModerations implements the method to list the moderation decisions of surveys
+func (r *SurveyRepository) ListModerations(surveyIDs ...uint) (moderations []model.Moderation, err error) {
+	moderations = make([]model.Moderation, 0)
+
+	result := r.db.Where("survey_refer IN ?", surveyIDs).Order("id").Find(&moderations)
+
+	if err = result.Error; err != nil {
+		return nil, err
+	}
+
+	return moderations, nil
+}
+
+// ListPrivacyReleases implements the method to list the differentially private publications of a survey
+func (r *SurveyRepository) ListPrivacyReleases(surveyID uint) (releases []model.PrivacyRelease, err error) {
+	releases = make([]model.PrivacyRelease, 0)
+
+	result := r.db.Where("survey_refer = ?", surveyID).Order("id").Find(&releases)
+
+	if err = result.Error; err != nil {
+		return nil, err
+	}
+
+	return releases, nil
+}
+
+// CreatePrivacyRelease implements the method to record a publication against the privacy budget
+func (r *SurveyRepository) CreatePrivacyRelease(release *model.PrivacyRelease) error {
+	result := r.db.Create(release)
+
+	if err := result.Error; err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ListTransitions implements the method to list the status history of a survey
+func (r *SurveyRepository) ListTransitions(id uint) (transitions []model.SurveyTransition, err error) {
+	transitions = make([]model.SurveyTransition, 0)
+
+	result := r.db.Where("survey_refer = ?", id).Order("id").Find(&transitions)
+
+	if err = result.Error; err != nil {
+		return nil, err
+	}
+
+	return transitions, nil
+}
+
 // Create implements the method to persist a new survey
 func (r *SurveyRepository) CreateSurvey(surveyCreate *model.Survey) (_ *model.Survey, err error) {
-	result := r.db.Create(&surveyCreate) // pass pointer of data to Create
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		// review records are only written by the reviewers, votes only by the ballots
+		if err := tx.Omit("Moderations", "Questions.Votes", "Questions.Choices.Votes").Create(&surveyCreate).Error; err != nil {
+			return err
+		}
 
-	if err = result.Error; err != nil {
+		return createEligibleValues(tx, surveyCreate.Eligibility.Values(surveyCreate.ID))
+	})
+
+	if err != nil {
 		return nil, err
 	}
 
 	return surveyCreate, nil
 }
+
+// createEligibleValues stores the set valued eligibility criteria of a survey
+func createEligibleValues(tx *gorm.DB, values []model.EligibleValue) error {
+	if len(values) == 0 {
+		return nil
+	}
+
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&values).Error
+}
+
+// questionColumns are the question fields selected by the survey tree queries
+const questionColumns = "q.id AS question_id, q.value AS question_value, q.type AS question_type, q.min_choices, q.max_choices, q.min_number, q.max_number"
+
+// resultColumns are the tallied vote fields selected by the survey result queries.
+// Numeric and text questions have a single null choice
+const resultColumns = "CASE WHEN c.id IS NULL THEN COALESCE(qt.count, 0) ELSE COALESCE(ct.count, 0) END AS vote_count, " +
+	"CASE WHEN ct.count > 0 THEN CAST(ct.rank_sum AS REAL) / ct.count END AS mean_rank, COALESCE(qt.respondents, 0) AS respondents, " +
+	"qt.number_min, qt.number_max, CASE WHEN qt.count > 0 AND qt.number_min IS NOT NULL THEN qt.number_sum / qt.count END AS number_mean"
+
+// voteJoin joins choice votes, and the choiceless votes of numeric and text questions
+const voteJoin = "LEFT JOIN votes AS v ON v.deleted_at IS NULL AND (v.choice_refer = c.id OR (c.id IS NULL AND v.question_refer = q.id))"
+
+// tallyJoin joins the choice tallies and the question tallies
+const tallyJoin = "LEFT JOIN tallies AS ct ON ct.question_refer = q.id AND ct.choice_refer = c.id LEFT JOIN tallies AS qt ON qt.question_refer = q.id AND qt.choice_refer = 0"
+
+// surveyRow holds the question and choice columns of one joined row, and the
+// aggregated votes of the choice for result queries
+type surveyRow struct {
+	question    model.Question
+	choiceID    sql.NullInt64
+	choiceValue sql.NullString
+	voteCount   int
+	meanRank    *float64
+	respondents int
+	numberMin   *float64
+	numberMax   *float64
+	numberMean  *float64
+}
+
+// appendSurveyRow merges a joined row into the question tree of the survey
+func appendSurveyRow(survey *model.Survey, row *surveyRow) (question *model.Question, choice *model.Choice) {
+	// Check if question exists in survey
+	question = survey.Question(row.question.ID)
+	if question == nil {
+		row.question.Choices = make([]model.Choice, 0)
+		survey.Questions = append(survey.Questions, row.question)
+		question = &survey.Questions[len(survey.Questions)-1]
+	}
+
+	// choice id is null for numeric and text questions
+	if !row.choiceID.Valid {
+		return question, nil
+	}
+	choiceID := uint(row.choiceID.Int64)
+
+	// Check if choice exists in question
+	for i := range question.Choices {
+		if question.Choices[i].ID == choiceID {
+			return question, &question.Choices[i]
+		}
+	}
+
+	newChoice := model.Choice{}
+	newChoice.ID = choiceID
+	newChoice.QuestionRefer = question.ID
+	newChoice.Value = row.choiceValue.String
+	question.Choices = append(question.Choices, newChoice)
+
+	return question, &question.Choices[len(question.Choices)-1]
+}
+
+// appendResultRow merges a joined row with its vote counts into the question tree of the survey
+func appendResultRow(survey *model.Survey, row *surveyRow) {
+	question, choice := appendSurveyRow(survey, row)
+
+	if question.Summary == nil {
+		question.Summary = &model.Summary{Respondents: row.respondents}
+	}
+
+	if choice != nil {
+		choice.VoteCount = row.voteCount
+		if question.Type == model.QuestionTypeRanking {
+			choice.MeanRank = row.meanRank
+		}
+		return
+	}
+
+	question.Summary.Count = row.voteCount
+	if question.Type == model.QuestionTypeNumeric {
+		question.Summary.Min = row.numberMin
+		question.Summary.Max = row.numberMax
+		question.Summary.Mean = row.numberMean
+	}
+}
+
+// summarizeSurvey summarizes the vote counts of every question by its type
+func summarizeSurvey(survey *model.Survey) {
+	for i := range survey.Questions {
+		survey.Questions[i].Summarize()
+	}
+}
+
+// voterFilter returns the where clause selecting surveys `s` the voter is eligible
+// for and has not voted in yet, unless the survey allows ballot changes
+func voterFilter(voter *model.User, now time.Time) (where string, args []interface{}) {
+	age := model.Age(voter.BirthDate, now)
+	where = "(NOT `s`.`residents_only` OR ?) AND (`s`.`min_age` IS NULL OR `s`.`min_age` <= ?) AND (`s`.`max_age` IS NULL OR `s`.`max_age` >= ?)"
+	args = []interface{}{voter.IsResident != nil && *voter.IsResident, age, age}
+
+	sets := []struct {
+		attribute model.EligibilityAttribute
+		value     string
+	}{
+		{model.EligibilityAttributeBirthSex, string(voter.BirthSex)},
+		{model.EligibilityAttributeGenderIdentity, string(voter.GenderIdentity)},
+		{model.EligibilityAttributeInvitee, voter.IDNumber},
+	}
+	for _, set := range sets {
+		where += " AND (NOT EXISTS (SELECT 1 FROM eligible_values AS ev WHERE ev.survey_refer = s.id AND ev.attribute = ?)" +
+			" OR EXISTS (SELECT 1 FROM eligible_values AS ev WHERE ev.survey_refer = s.id AND ev.attribute = ? AND ev.value = ?))"
+		args = append(args, set.attribute, set.attribute, set.value)
+	}
+
+	// ballots are found by their responses, or participations for anonymous surveys
+	where += " AND (`s`.`ballot_changes` OR NOT EXISTS (SELECT 1 FROM responses AS rs JOIN questions AS q ON q.id = rs.question_refer WHERE q.survey_refer = s.id AND rs.user_refer = ? AND rs.deleted_at IS NULL))" +
+		" AND NOT EXISTS (SELECT 1 FROM participations AS p WHERE p.survey_refer = s.id AND p.user_refer = ?)"
+	args = append(args, voter.ID, voter.ID)
+
+	return where, args
+}
+
+// eligibleUsersFilter returns the where clause selecting users `u` the eligibility
+// criteria admit at the given time, it mirrors model.Eligibility.Admits
+func eligibleUsersFilter(eligibility *model.Eligibility, now time.Time) (where string, args []interface{}) {
+	where = "1 = 1"
+	if eligibility.ResidentsOnly {
+		where += " AND `u`.`is_resident`"
+	}
+	// birth dates are compared by their stored date, like ages are computed
+	if eligibility.MinAge != nil {
+		where += " AND substr(`u`.`birth_date`, 1, 10) <= ?"
+		args = append(args, now.AddDate(-int(*eligibility.MinAge), 0, 0).Format("2006-01-02"))
+	}
+	if eligibility.MaxAge != nil {
+		where += " AND substr(`u`.`birth_date`, 1, 10) > ?"
+		args = append(args, now.AddDate(-int(*eligibility.MaxAge)-1, 0, 0).Format("2006-01-02"))
+	}
+	if len(eligibility.BirthSexes) > 0 {
+		where += " AND `u`.`birth_sex` IN ?"
+		args = append(args, eligibility.BirthSexes)
+	}
+	if len(eligibility.GenderIdentities) > 0 {
+		where += " AND `u`.`gender_identity` IN ?"
+		args = append(args, eligibility.GenderIdentities)
+	}
+	if len(eligibility.Invitees) > 0 {
+		where += " AND `u`.`id_number` IN ?"
+		args = append(args, eligibility.Invitees)
+	}
+
+	return where, args
+}
+
+// publicFilter selects the surveys `s` that may be listed publicly, private surveys
+// are only reached through their invitations
+const publicFilter = "NOT `s`.`private`"
+
+// statusFilter returns the where clause selecting surveys `s` in the given
+// lifecycle status at the given time, it mirrors model.Survey.Status
+func statusFilter(status model.SurveyStatus, now time.Time) (where string, args []interface{}) {
+	switch status {
+	case model.SurveyStatusScheduled:
+		return "`s`.`confirm_status` = ? AND `s`.`date_start` > ?", []interface{}{model.ConfirmStatusAccepted, now}
+	case model.SurveyStatusActive:
+		return "`s`.`confirm_status` = ? AND `s`.`date_start` <= ? AND `s`.`date_end` >= ?", []interface{}{model.ConfirmStatusAccepted, now, now}
+	case model.SurveyStatusClosed:
+		return "`s`.`confirm_status` = ? AND `s`.`date_end` < ?", []interface{}{model.ConfirmStatusAccepted, now}
+	}
+
+	return "`s`.`confirm_status` = ?", []interface{}{string(status)}
+}
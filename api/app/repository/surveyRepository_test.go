@@ -1 +1,91 @@
 package repository
+
+import (
+	"dou-survey/app/model"
+	"dou-survey/internal/logger"
+	"dou-survey/internal/storage"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestVoteAnonymouslyKeepsNoCastOrder(t *testing.T) {
+	os.Setenv("DB_CONNECTION_STRING", filepath.Join(t.TempDir(), "survey.db"))
+	apiLogger := logger.NewAPILogger()
+	apiLogger.InitLogger()
+	db := storage.InitializeDB(apiLogger)
+	repo := NewSurveyRepository(db, apiLogger)
+
+	now := time.Now().UTC()
+	survey, err := repo.CreateSurvey(&model.Survey{UserRefer: 100, Anonymous: true, Subject: "s", Description: "d", DateStart: now.Add(-time.Hour), DateEnd: now.Add(time.Hour),
+		Questions: []model.Question{{Value: "q", Choices: []model.Choice{{Value: "a"}, {Value: "b"}}}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = repo.UpdateByID(survey.ID, model.Survey{ConfirmStatus: model.ConfirmStatusAccepted}); err != nil {
+		t.Fatal(err)
+	}
+
+	// later voters have lower user IDs
+	const voters = 8
+	receipts := make([]string, 0, voters)
+	for i := voters; i > 0; i-- {
+		user := &model.User{IDNumber: fmt.Sprint(i), BirthSex: model.BirthSexWoman, BirthDate: time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC)}
+		user.ID = uint(i)
+		if err = db.Create(user).Error; err != nil {
+			t.Fatal(err)
+		}
+		_, receipt, err := repo.Vote(user.ID, survey.ID, []model.Answer{{QuestionID: survey.Questions[0].ID, Choices: []uint{uint(i % 2)}}}, "", now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		receipts = append(receipts, receipt)
+	}
+
+	if err = db.Exec("SELECT rowid FROM participations").Error; err == nil {
+		t.Error("participations keep a rowid")
+	}
+	if err = db.Exec("SELECT rowid FROM pending_ballots").Error; err == nil {
+		t.Error("pending ballots keep a rowid")
+	}
+
+	participants := make([]uint, 0)
+	if err = db.Raw("SELECT user_refer FROM participations").Scan(&participants).Error; err != nil {
+		t.Fatal(err)
+	}
+	if !sort.SliceIsSorted(participants, func(i, j int) bool { return participants[i] < participants[j] }) {
+		t.Errorf("participations = %v, want them in key order", participants)
+	}
+
+	votes := make([]model.Vote, 0)
+	if err = db.Order("id").Find(&votes).Error; err != nil {
+		t.Fatal(err)
+	}
+	inCastOrder := len(votes) == voters
+	for i := range votes {
+		inCastOrder = inCastOrder && votes[i].BallotKey == receipts[i]
+	}
+	if inCastOrder {
+		t.Error("anonymous vote keys follow the cast order")
+	}
+
+	entries, err := repo.ListBallotLog(survey.ID)
+	if err != nil || len(entries) != 0 {
+		t.Fatalf("ListBallotLog() = %v, %v, want no entries while voting", entries, err)
+	}
+	if err = repo.ChainPendingBallots(survey.ID); err != nil {
+		t.Fatal(err)
+	}
+	if entries, err = repo.ListBallotLog(survey.ID); err != nil || len(entries) != voters {
+		t.Fatalf("ListBallotLog() = %v, %v, want %d entries", entries, err, voters)
+	}
+	sort.Strings(receipts)
+	for i, entry := range entries {
+		if entry.Receipt != receipts[i] {
+			t.Fatalf("entry %d has receipt %s, want the log in receipt order", entry.Sequence, entry.Receipt)
+		}
+	}
+}
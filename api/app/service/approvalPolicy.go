@@ -0,0 +1,64 @@
+package service
+
+import (
+	"dou-survey/app/model"
+	"os"
+	"strconv"
+	"time"
+)
+
+// ApprovalPolicy decides when a survey waiting for confirmation gets confirmed
+// and how long its review may take. A single decline always vetoes the survey.
+type ApprovalPolicy struct {
+	RequiredApprovals int           // distinct employee approvals needed
+	SeniorAudience    uint          // surveys expecting at least this many respondents need a senior approval, 0 disables
+	ClaimWindow       time.Duration // how long a claimed survey stays locked to its reviewer
+	ReviewSLA         time.Duration // surveys waiting longer are overdue
+}
+
+// approvalPolicyFromEnv reads the policy from SURVEY_REQUIRED_APPROVALS, SURVEY_SENIOR_AUDIENCE,
+// SURVEY_CLAIM_MINUTES and SURVEY_REVIEW_SLA_HOURS
+func approvalPolicyFromEnv() ApprovalPolicy {
+	policy := ApprovalPolicy{RequiredApprovals: 1, ClaimWindow: 30 * time.Minute, ReviewSLA: 48 * time.Hour}
+
+	if required, err := strconv.Atoi(os.Getenv("SURVEY_REQUIRED_APPROVALS")); err == nil && required > 0 {
+		policy.RequiredApprovals = required
+	}
+	if audience, err := strconv.ParseUint(os.Getenv("SURVEY_SENIOR_AUDIENCE"), 10, 32); err == nil {
+		policy.SeniorAudience = uint(audience)
+	}
+	if minutes, err := strconv.Atoi(os.Getenv("SURVEY_CLAIM_MINUTES")); err == nil && minutes > 0 {
+		policy.ClaimWindow = time.Duration(minutes) * time.Minute
+	}
+	if hours, err := strconv.Atoi(os.Getenv("SURVEY_REVIEW_SLA_HOURS")); err == nil && hours > 0 {
+		policy.ReviewSLA = time.Duration(hours) * time.Hour
+	}
+
+	return policy
+}
+
+// SeniorRequired reports whether the survey needs the approval of a senior employee
+func (p ApprovalPolicy) SeniorRequired(survey *model.Survey) bool {
+	return survey.Sensitive || (p.SeniorAudience > 0 && survey.Audience >= p.SeniorAudience)
+}
+
+// Review fills the policy side of the approval progress of a survey
+func (p ApprovalPolicy) Review(survey *model.Survey, review *model.Review, now time.Time) {
+	review.RequiredApprovals = p.RequiredApprovals
+	review.SeniorRequired = p.SeniorRequired(survey)
+
+	if survey.SubmittedAt != nil {
+		waiting := now.Sub(*survey.SubmittedAt)
+		review.WaitingHours = int(waiting.Hours())
+		review.Overdue = waiting > p.ReviewSLA
+	}
+}
+
+// Approved reports whether the review satisfies the policy
+func (p ApprovalPolicy) Approved(review *model.Review) bool {
+	if review.Approvals < review.RequiredApprovals {
+		return false
+	}
+
+	return !review.SeniorRequired || review.SeniorApproved
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"dou-survey/app/model"
+	"testing"
+	"time"
+)
+
+func TestApprovalPolicyApproved(t *testing.T) {
+	policy := ApprovalPolicy{RequiredApprovals: 2, SeniorAudience: 1000}
+
+	tests := []struct {
+		name           string
+		survey         model.Survey
+		approvals      int
+		seniorApproved bool
+		want           bool
+	}{
+		{"not enough approvals", model.Survey{}, 1, false, false},
+		{"enough approvals", model.Survey{}, 2, false, true},
+		{"sensitive without senior", model.Survey{Sensitive: true}, 2, false, false},
+		{"sensitive with senior", model.Survey{Sensitive: true}, 2, true, true},
+		{"large audience without senior", model.Survey{Audience: 1000}, 3, false, false},
+		{"small audience without senior", model.Survey{Audience: 999}, 2, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			review := &model.Review{Approvals: tt.approvals, SeniorApproved: tt.seniorApproved}
+			policy.Review(&tt.survey, review, time.Now())
+			if got := policy.Approved(review); got != tt.want {
+				t.Errorf("Approved() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApprovalPolicyReviewWaiting(t *testing.T) {
+	policy := ApprovalPolicy{RequiredApprovals: 1, ReviewSLA: 48 * time.Hour}
+	now := time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		submittedAt time.Time
+		wantHours   int
+		wantOverdue bool
+	}{
+		{"within sla", now.Add(-47 * time.Hour), 47, false},
+		{"overdue", now.Add(-49 * time.Hour), 49, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			review := &model.Review{}
+			policy.Review(&model.Survey{SubmittedAt: &tt.submittedAt}, review, now)
+			if review.WaitingHours != tt.wantHours || review.Overdue != tt.wantOverdue {
+				t.Errorf("Review() = %v hours overdue %v, want %v hours overdue %v", review.WaitingHours, review.Overdue, tt.wantHours, tt.wantOverdue)
+			}
+		})
+	}
+}
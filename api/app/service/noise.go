@@ -0,0 +1,194 @@
+package service
+
+import (
+	"crypto/sha256"
+	"dou-survey/app/model"
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// noise perturbs the counts of one release of a survey. The noise added to a
+// count only depends on the release seed and the key of the count, so repeated
+// queries return the same numbers and spend no further budget
+type noise struct {
+	mechanism model.NoiseMechanism
+	seed      int64
+	scale     float64 // laplace b or gaussian sigma
+}
+
+// newNoise calibrates the noise to the epsilon of the survey. Sensitivity is the
+// number of counts a single voter can change, by at most one each
+func newNoise(survey *model.Survey, release *model.PrivacyRelease, sensitivity int) noise {
+	n := noise{mechanism: survey.Noise, seed: release.Seed}
+
+	switch survey.Noise {
+	case model.NoiseLaplace:
+		n.scale = float64(sensitivity) / release.Epsilon
+	case model.NoiseGaussian:
+		n.scale = math.Sqrt(2*math.Log(1.25/model.NoiseDelta)) * math.Sqrt(float64(sensitivity)) / release.Epsilon
+	}
+
+	return n
+}
+
+// privacy describes the noise for the response
+func (n noise) privacy(release *model.PrivacyRelease) *model.Privacy {
+	privacy := &model.Privacy{Noisy: true, Mechanism: n.mechanism, Epsilon: release.Epsilon}
+	if n.mechanism == model.NoiseGaussian {
+		privacy.Delta = model.NoiseDelta
+	}
+
+	return privacy
+}
+
+// uniform returns a number in (0, 1) derived from the seed and the key
+func (n noise) uniform(key string, i int) float64 {
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%d:%s:%d", n.seed, key, i)))
+
+	return (float64(binary.BigEndian.Uint64(hash[:8])>>11) + 0.5) / (1 << 53)
+}
+
+// count perturbs a count, the result is rounded and never negative
+func (n noise) count(key string, value int) int {
+	var x float64
+
+	switch n.mechanism {
+	case model.NoiseLaplace:
+		u := n.uniform(key, 0) - 0.5
+		x = -n.scale * math.Copysign(1, u) * math.Log(1-2*math.Abs(u))
+	case model.NoiseGaussian:
+		// Box-Muller
+		x = n.scale * math.Sqrt(-2*math.Log(n.uniform(key, 0))) * math.Cos(2*math.Pi*n.uniform(key, 1))
+	}
+
+	noisy := int(math.Round(float64(value) + x))
+	if noisy < 0 {
+		return 0
+	}
+
+	return noisy
+}
+
+// maxSelections is the number of choices a voter can select in a question
+func maxSelections(question *model.Question) int {
+	switch question.Type {
+	case model.QuestionTypeMulti:
+		if question.MaxChoices != 0 {
+			return int(question.MaxChoices)
+		}
+		return len(question.Choices)
+	case model.QuestionTypeRanking:
+		return len(question.Choices)
+	}
+
+	return 1
+}
+
+// resultsSensitivity counts the choice, answer and respondent counts a voter can change
+func resultsSensitivity(survey *model.Survey) (sensitivity int) {
+	for i := range survey.Questions {
+		question := &survey.Questions[i]
+		sensitivity += maxSelections(question)
+		if question.HasChoices() {
+			sensitivity++
+		}
+	}
+
+	return sensitivity
+}
+
+// demographicsSensitivity counts the crosstab cells a voter can change, in every
+// dimension a voter adds to the respondents and to the first preferences of one group
+func demographicsSensitivity(survey *model.Survey) (sensitivity int) {
+	for i := range survey.Questions {
+		question := &survey.Questions[i]
+		if !question.HasChoices() {
+			continue
+		}
+
+		selections := 1
+		if question.Type == model.QuestionTypeMulti {
+			selections = maxSelections(question)
+		}
+		sensitivity += len(model.CrosstabDimensions) * (1 + selections)
+	}
+
+	return sensitivity
+}
+
+// results replaces the counts of the survey with noisy counts. Numeric statistics
+// and mean ranks are left out as they can not be perturbed this way
+func (n noise) results(survey *model.Survey) {
+	for i := range survey.Questions {
+		question := &survey.Questions[i]
+
+		if !question.HasChoices() {
+			// a voter gives one answer at most
+			count := n.count(fmt.Sprintf("answers:%d", question.ID), question.Summary.Count)
+			question.Summary = &model.Summary{Count: count, Respondents: count}
+			continue
+		}
+
+		respondents := n.count(fmt.Sprintf("respondents:%d", question.ID), question.Summary.Respondents)
+		question.Summary = &model.Summary{Respondents: respondents}
+		for j := range question.Choices {
+			choice := &question.Choices[j]
+			choice.VoteCount = n.count(fmt.Sprintf("choice:%d", choice.ID), choice.VoteCount)
+			if choice.VoteCount > respondents {
+				// nobody selects a choice twice
+				choice.VoteCount = respondents
+			}
+			choice.MeanRank = nil
+		}
+		question.Summarize()
+	}
+}
+
+// crosstabRows perturbs the crosstab of the survey. Every group of every dimension
+// gets a row, otherwise the missing rows would tell which groups nobody is in
+func (n noise) crosstabRows(survey *model.Survey, rows []model.CrosstabRow) []model.CrosstabRow {
+	counts := make(map[string]int)
+	respondents := make(map[string]int)
+	for _, row := range rows {
+		counts[fmt.Sprintf("count:%d:%s:%s", row.ChoiceID, row.Dimension, row.Group)] = row.Count
+		respondents[fmt.Sprintf("respondents:%d:%s:%s", row.QuestionID, row.Dimension, row.Group)] = row.Respondents
+	}
+
+	noisy := make([]model.CrosstabRow, 0)
+	for _, question := range survey.Questions {
+		if !question.HasChoices() {
+			continue
+		}
+
+		for _, dimension := range model.CrosstabDimensions {
+			for _, group := range model.CrosstabGroups(dimension) {
+				key := fmt.Sprintf("respondents:%d:%s:%s", question.ID, dimension, group)
+				groupRespondents := n.count(key, respondents[key])
+
+				for _, choice := range question.Choices {
+					key := fmt.Sprintf("count:%d:%s:%s", choice.ID, dimension, group)
+					row := model.CrosstabRow{
+						QuestionID:  question.ID,
+						ChoiceID:    choice.ID,
+						Dimension:   dimension,
+						Group:       group,
+						Count:       n.count(key, counts[key]),
+						Respondents: groupRespondents,
+					}
+					if row.Count > row.Respondents {
+						// nobody selects a choice twice
+						row.Count = row.Respondents
+					}
+					if row.Respondents > 0 {
+						row.Percentage = math.Round(float64(row.Count)*10000/float64(row.Respondents)) / 100
+					}
+
+					noisy = append(noisy, row)
+				}
+			}
+		}
+	}
+
+	return noisy
+}
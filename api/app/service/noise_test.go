@@ -0,0 +1,45 @@
+package service
+
+import (
+	"dou-survey/app/model"
+	"testing"
+)
+
+func TestNoiseCountIsRepeatable(t *testing.T) {
+	survey := &model.Survey{Noise: model.NoiseLaplace}
+	release := &model.PrivacyRelease{Epsilon: 0.5, Seed: 42}
+	n := newNoise(survey, release, 2)
+
+	for _, key := range []string{"choice:1", "choice:2", "answers:3"} {
+		first := n.count(key, 10)
+		if first < 0 {
+			t.Errorf("count(%q) = %v, want a non negative count", key, first)
+		}
+		if again := n.count(key, 10); again != first {
+			t.Errorf("count(%q) = %v then %v, want the same noise", key, first, again)
+		}
+	}
+
+	other := newNoise(survey, &model.PrivacyRelease{Epsilon: 0.5, Seed: 43}, 2)
+	differs := false
+	for _, key := range []string{"a", "b", "c", "d", "e"} {
+		differs = differs || other.count(key, 100) != n.count(key, 100)
+	}
+	if !differs {
+		t.Errorf("count() is the same for different seeds")
+	}
+}
+
+func TestResultsSensitivity(t *testing.T) {
+	choices := []model.Choice{{Value: "a"}, {Value: "b"}, {Value: "c"}}
+	survey := &model.Survey{Questions: []model.Question{
+		{Type: model.QuestionTypeSingle, Choices: choices},
+		{Type: model.QuestionTypeMulti, Choices: choices, MaxChoices: 2},
+		{Type: model.QuestionTypeRanking, Choices: choices},
+		{Type: model.QuestionTypeText},
+	}}
+
+	if got := resultsSensitivity(survey); got != 10 {
+		t.Errorf("resultsSensitivity() = %v, want 10", got)
+	}
+}
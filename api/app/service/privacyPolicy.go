@@ -0,0 +1,33 @@
+package service
+
+import (
+	"dou-survey/app/model"
+	"os"
+	"strconv"
+)
+
+// PrivacyPolicy limits what is disclosed about the voters of a survey.
+type PrivacyPolicy struct {
+	MinCellSize int // demographic groups with fewer voters are merged or suppressed
+}
+
+// privacyPolicyFromEnv reads the policy from SURVEY_MIN_CELL_SIZE
+func privacyPolicyFromEnv() PrivacyPolicy {
+	policy := PrivacyPolicy{MinCellSize: 5}
+
+	if size, err := strconv.Atoi(os.Getenv("SURVEY_MIN_CELL_SIZE")); err == nil && size > 0 {
+		policy.MinCellSize = size
+	}
+
+	return policy
+}
+
+// CellSize returns the minimum cell size of the survey, owners can raise the
+// deployment default but not lower it
+func (p PrivacyPolicy) CellSize(survey *model.Survey) int {
+	if int(survey.MinCellSize) > p.MinCellSize {
+		return int(survey.MinCellSize)
+	}
+
+	return p.MinCellSize
+}
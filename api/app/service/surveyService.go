@@ -1,39 +1,96 @@
 package service
 
 import (
+	"crypto/rand"
+	appError "dou-survey/app/error"
+	"dou-survey/app/helpers"
 	"dou-survey/app/model"
 	"dou-survey/app/repository"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
 )
 
 //SurveyServiceInterface define the survey service interface methods
 type SurveyServiceInterface interface {
 	GetConfirmed(surveyID uint) (confirmStatus model.ConfirmStatus, err error)
-	UpdateConfirmStatus(surveyID uint, status model.ConfirmStatus) (err error)
-	ChoiceVotersInfo(choiceID uint) (voters []model.UserReduced, err error)
-	Vote(userID, surveyID uint, votes []uint) (created []model.Vote, err error)
+	UpdateConfirmStatus(reviewer *model.Employee, surveyID uint, status model.ConfirmStatus, reason model.DeclineReason, comment string) (err error)
+	ListModerations(userID, surveyID uint) (moderations []model.Moderation, err error)
+	ListTransitions(surveyID uint) (transitions []model.SurveyTransition, err error)
+	ChoiceVotersInfo(choiceID uint) (demographics *model.ChoiceDemographics, err error)
+	Crosstab(surveyID uint) (crosstab *model.Crosstab, err error)
+	SeriesResults(surveyID uint, dimension model.CrosstabDimension, group string) (series *model.SeriesResults, err error)
+	Vote(userID, surveyID uint, answers []model.Answer, token string) (created []model.Vote, receipt string, err error)
 	VotedAlready(userID, surveyID uint) (voted bool, err error)
-	ListWaitingConfirmation(limit, offset uint) (surveys []model.Survey, err error)
-	ListActive(limit, offset uint) (survey []model.Survey, err error)
+	MyBallot(userID, surveyID uint) (ballot *model.Ballot, err error)
+	SaveDraft(userID, surveyID uint, answers []model.Answer) (draft *model.DraftBallot, err error)
+	FindDraft(userID, surveyID uint) (draft *model.DraftBallot, err error)
+	SubmitDraft(userID, surveyID uint, token string) (created []model.Vote, receipt string, err error)
+	DeleteClosedDrafts() (deleted int64, err error)
+	ListWaitingConfirmation(reviewerID, limit, offset uint) (surveys []model.Survey, err error)
+	ListActive(limit, offset uint, voter *model.User) (survey []model.Survey, err error)
 	ListResults(limit, offset uint) (survey []model.Survey, err error)
 	CountWaitingConfirmation() (count int, err error)
-	CountActive() (count int, err error)
+	SummarizeWaitingConfirmation() (queue *model.ReviewQueue, err error)
+	ReconcileTallies() (drift []model.TallyDrift, err error)
+	Claim(reviewer *model.Employee, surveyID uint) (claimedUntil time.Time, err error)
+	Release(reviewer *model.Employee, surveyID uint) (err error)
+	MarkSensitive(reviewer *model.Employee, surveyID uint, sensitive bool) (err error)
+	CountActive(voter *model.User) (count int, err error)
 	CountResults() (count int, err error)
 	FindByIDReduced(userId uint) (survey *model.Survey, err error)
-	FindByIDWithVotes(userId uint) (survey *model.Survey, err error)
+	FindByIDWithResults(userId uint) (survey *model.Survey, err error)
+	FindByIDWithVotes(surveyID uint) (survey *model.Survey, err error)
+	BallotLog(surveyID uint) (log *model.BallotLog, err error)
+	CheckReceipt(receipt string) (check *model.ReceiptCheck, err error)
+	PublishBallotRoots() (roots map[uint]string, err error)
+	ExportResults(surveyID uint, format model.ExportFormat, w io.Writer) (err error)
+	ExportResponses(surveyID uint, format model.ExportFormat, w io.Writer) (err error)
+	ExportCodedResponses(surveyID uint, format model.ExportFormat, w io.Writer) (err error)
+	Codebook(surveyID uint) (codebook *model.Codebook, err error)
 	FindByIDWithoutVotes(userId uint) (survey *model.Survey, err error)
 	CountQuestion(id uint) (count int, err error)
 	Create(create *model.Survey) (survey *model.Survey, err error)
+	Import(userID uint, definition *model.SurveyDefinition) (survey *model.Survey, err error)
+	ExportDefinition(userID, surveyID uint) (definition *model.SurveyDefinition, err error)
+	Clone(userID, surveyID uint, request model.CloneRequest) (survey *model.Survey, err error)
+	LinkWave(userID, surveyID, previousID uint) (err error)
+	UnlinkWave(userID, surveyID uint) (err error)
+	SaveTemplate(userID, surveyID uint, request model.TemplateRequest) (template *model.SurveyTemplate, err error)
+	PublishTemplate(employee *model.Employee, surveyID uint, request model.TemplateRequest) (template *model.SurveyTemplate, err error)
+	ListTemplates(userID uint) (templates []model.SurveyTemplate, err error)
+	FindTemplate(userID, templateID uint) (template *model.SurveyTemplate, err error)
+	UseTemplate(userID, templateID uint, request model.CloneRequest) (survey *model.Survey, err error)
+	DeleteTemplate(userID, templateID uint) (err error)
+	UnpublishTemplate(templateID uint) (err error)
+	CheckOwner(userID, surveyID uint) (err error)
+	FindOwned(userID, surveyID uint) (survey *model.Survey, err error)
+	QuotaStatus(userID, surveyID uint) (status *model.QuotaStatus, err error)
+	CreateInvitations(userID, surveyID uint, request model.InvitationRequest) (invitations []model.Invitation, err error)
+	ListInvitations(userID, surveyID uint) (invitations []model.Invitation, err error)
+	RevokeInvitation(userID, surveyID, invitationID uint) (err error)
+	Update(userID, surveyID uint, update model.Survey) (err error)
+	Replace(userID, surveyID uint, replace *model.Survey) (survey *model.Survey, err error)
+	Remove(userID, surveyID uint) (err error)
+	Submit(userID, surveyID uint) (err error)
 }
 
 // billingService handles communication with the survey repository
 type SurveyService struct {
-	surveyRepo repository.SurveyRepositoryInterface
+	surveyRepo     repository.SurveyRepositoryInterface
+	approvalPolicy ApprovalPolicy
+	privacyPolicy  PrivacyPolicy
 }
 
 // NewSurveyService implements the survey service interface.
 func NewSurveyService(surveyRepo repository.SurveyRepositoryInterface) SurveyServiceInterface {
 	return &SurveyService{
 		surveyRepo,
+		approvalPolicyFromEnv(),
+		privacyPolicyFromEnv(),
 	}
 }
 
@@ -41,52 +98,992 @@ func (s *SurveyService) GetConfirmed(surveyID uint) (confirmStatus model.Confirm
 	return s.surveyRepo.GetConfirmed(surveyID)
 }
 
-func (s *SurveyService) UpdateConfirmStatus(surveyID uint, status model.ConfirmStatus) (err error) {
-	return s.surveyRepo.UpdateByID(surveyID, model.Survey{ConfirmStatus: status})
+// UpdateConfirmStatus lets an employee approve, decline or archive a survey.
+// Approvals confirm the survey once the approval policy is met, a decline vetoes
+// it right away and must give a reason that is shown to the owner
+func (s *SurveyService) UpdateConfirmStatus(reviewer *model.Employee, surveyID uint, status model.ConfirmStatus, reason model.DeclineReason, comment string) (err error) {
+	survey, err := s.FindByIDReduced(surveyID)
+	if err != nil {
+		return err
+	}
+	if survey.ID == 0 {
+		return appError.ErrNotFound
+	}
+
+	switch status {
+	case model.ConfirmStatusAccepted, model.ConfirmStatusDeclined, model.ConfirmStatusArchived:
+	default:
+		// drafts and submissions are owner actions
+		return &appError.TransitionError{From: string(survey.Status(time.Now().UTC())), To: string(status)}
+	}
+
+	if status == model.ConfirmStatusArchived {
+		return s.transition(reviewer.UserRefer, survey, status)
+	}
+
+	if status == model.ConfirmStatusDeclined {
+		if !reason.Valid() {
+			return errors.New("a valid reason is required to decline a survey")
+		}
+		if reason == model.DeclineReasonOther && strings.TrimSpace(comment) == "" {
+			return errors.New("a comment is required when the decline reason is other")
+		}
+	} else if reason != "" {
+		return errors.New("reason can only be given when declining a survey")
+	}
+
+	if survey.UserRefer == reviewer.UserRefer {
+		return errors.New("you can not review your own survey")
+	}
+	if !canTransition(survey.Status(time.Now().UTC()), status) {
+		return &appError.TransitionError{From: string(survey.Status(time.Now().UTC())), To: string(status)}
+	}
+	if survey.Claimed(reviewer.UserRefer, time.Now().UTC()) {
+		return appError.ErrSurveyClaimed
+	}
+
+	review, err := s.reviewProgress(survey, reviewer.UserRefer)
+	if err != nil {
+		return err
+	}
+	if review == nil {
+		return errors.New("you already reviewed this survey")
+	}
+
+	moderation := &model.Moderation{
+		SurveyRefer:   surveyID,
+		Round:         survey.ReviewRound,
+		ReviewerRefer: reviewer.UserRefer,
+		Senior:        reviewer.Senior,
+		Decision:      status,
+		Reason:        reason,
+		Comment:       comment,
+	}
+
+	if status == model.ConfirmStatusDeclined {
+		return s.transition(reviewer.UserRefer, survey, status, moderation)
+	}
+
+	review.Approvals++
+	review.SeniorApproved = review.SeniorApproved || reviewer.Senior
+	if s.approvalPolicy.Approved(review) {
+		return s.transition(reviewer.UserRefer, survey, status, moderation)
+	}
+
+	// not enough approvals yet, keep waiting
+	if err = s.surveyRepo.CreateModeration(moderation); err != nil {
+		return err
+	}
+
+	// let the next reviewer pick it up
+	if err = s.surveyRepo.Release(surveyID, reviewer.UserRefer); err != nil {
+		return err
+	}
+
+	// another reviewer may have approved at the same time
+	review, err = s.reviewProgress(survey, 0)
+	if err != nil {
+		return err
+	}
+	if s.approvalPolicy.Approved(review) {
+		err = s.transition(reviewer.UserRefer, survey, status)
+		if errors.Is(err, appError.ErrStatusChanged) {
+			return nil
+		}
+		return err
+	}
+
+	return nil
+}
+
+// reviewProgress counts the approvals of the current review round, it returns nil
+// if the reviewer already decided in this round
+func (s *SurveyService) reviewProgress(survey *model.Survey, reviewerID uint) (review *model.Review, err error) {
+	moderations, err := s.surveyRepo.ListModerations(survey.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	review = &model.Review{}
+	if review.EligibleUsers, err = s.surveyRepo.CountEligibleUsers(&survey.Eligibility, survey.DateStart); err != nil {
+		return nil, err
+	}
+	survey.Audience = model.EstimateAudience(survey, review.EligibleUsers)
+	s.approvalPolicy.Review(survey, review, time.Now().UTC())
+	for _, moderation := range moderations {
+		if moderation.Round != survey.ReviewRound {
+			continue
+		}
+		if reviewerID != 0 && moderation.ReviewerRefer == reviewerID {
+			return nil, nil
+		}
+		if moderation.Decision == model.ConfirmStatusAccepted {
+			review.Approvals++
+			review.SeniorApproved = review.SeniorApproved || moderation.Senior
+		}
+	}
+
+	return review, nil
 }
 
-func (s *SurveyService) ChoiceVotersInfo(choiceID uint) (voters []model.UserReduced, err error) {
-	return s.surveyRepo.ChoiceVotersInfo(choiceID)
+// ListModerations returns the review decisions on a survey to its owner
+func (s *SurveyService) ListModerations(userID, surveyID uint) (moderations []model.Moderation, err error) {
+	if _, err = s.owned(userID, surveyID); err != nil {
+		return nil, err
+	}
+
+	return s.surveyRepo.ListModerations(surveyID)
 }
 
-func (s *SurveyService) Vote(userID, surveyID uint, votes []uint) (created []model.Vote, err error) {
-	return s.surveyRepo.Vote(userID, surveyID, votes)
+func (s *SurveyService) ListTransitions(surveyID uint) (transitions []model.SurveyTransition, err error) {
+	return s.surveyRepo.ListTransitions(surveyID)
+}
+
+// ChoiceVotersInfo describes the demographics of the voters of a choice of a finished
+// survey, groups smaller than the minimum cell size of the survey are merged. While
+// voting runs, the difference between two requests would single out a new voter
+func (s *SurveyService) ChoiceVotersInfo(choiceID uint) (demographics *model.ChoiceDemographics, err error) {
+	surveyID, err := s.surveyRepo.FindSurveyIDByChoice(choiceID)
+	if err != nil {
+		return nil, err
+	}
+
+	survey, err := s.finished(surveyID)
+	if err != nil {
+		return nil, err
+	}
+	if survey.Anonymous {
+		// the crosstab of the whole survey is still available
+		return nil, appError.ErrAnonymousSurvey
+	}
+
+	rows, privacy, err := s.demographics(survey, choiceID)
+	if err != nil {
+		return nil, err
+	}
+
+	demographics = model.NewChoiceDemographics(choiceID, rows, s.privacyPolicy.CellSize(survey))
+	demographics.Privacy = privacy
+
+	return demographics, nil
+}
+
+// Crosstab breaks the results of a finished survey down by the demographics of its voters
+func (s *SurveyService) Crosstab(surveyID uint) (crosstab *model.Crosstab, err error) {
+	survey, err := s.surveyRepo.FindByIDReduced(surveyID)
+	if err != nil {
+		return nil, err
+	}
+	if survey.ID == 0 {
+		return nil, appError.ErrNotFound
+	}
+
+	switch survey.Status(time.Now().UTC()) {
+	case model.SurveyStatusClosed, model.SurveyStatusArchived:
+	default:
+		return nil, errors.New("results are available after the survey has ended")
+	}
+
+	rows, privacy, err := s.demographics(survey, 0)
+	if err != nil {
+		return nil, err
+	}
+	minCellSize := s.privacyPolicy.CellSize(survey)
+
+	// question and choice texts
+	survey, err = s.surveyRepo.FindByIDWithoutVotes(surveyID)
+	if err != nil {
+		return nil, err
+	}
+
+	crosstab = model.NewCrosstab(survey, rows)
+	crosstab.Suppress(minCellSize)
+	crosstab.Privacy = privacy
+
+	return crosstab, nil
+}
+
+// SeriesResults compares the finished waves of the series of a survey, from the first
+// wave up to the survey, within a demographic dimension or one of its groups. Waves
+// still running are listed without results, private and unconfirmed waves are left out
+func (s *SurveyService) SeriesResults(surveyID uint, dimension model.CrosstabDimension, group string) (series *model.SeriesResults, err error) {
+	if err = model.ValidateSeriesFilter(dimension, group); err != nil {
+		return nil, err
+	}
+
+	// walk back the links, a wave whose dates were edited could close a loop
+	waves := make([]model.SeriesWave, 0)
+	crosstabs := make([]*model.Crosstab, 0)
+	seen := make(map[uint]bool)
+	for id := &surveyID; id != nil && !seen[*id]; {
+		seen[*id] = true
+
+		survey, err := s.surveyRepo.FindByIDReduced(*id)
+		if err != nil {
+			return nil, err
+		}
+		if survey.ID == 0 {
+			if len(waves) == 0 {
+				return nil, appError.ErrNotFound
+			}
+			// the earlier waves were removed
+			break
+		}
+		id = survey.PreviousWave
+
+		// private and unconfirmed waves are not public
+		status := survey.Status(time.Now().UTC())
+		if survey.Private || status == model.SurveyStatusDraft || status == model.SurveyStatusWaiting || status == model.SurveyStatusDeclined {
+			if survey.ID == surveyID {
+				return nil, appError.ErrNotFound
+			}
+			continue
+		}
+
+		wave := model.SeriesWave{SurveyID: survey.ID, Subject: survey.Subject, DateStart: survey.DateStart, DateEnd: survey.DateEnd}
+		var crosstab *model.Crosstab
+		if status == model.SurveyStatusScheduled || status == model.SurveyStatusActive {
+			// the wave is still running
+			wave.Unfinished = true
+		} else {
+			if crosstab, err = s.Crosstab(survey.ID); err != nil {
+				return nil, err
+			}
+			wave.MinCellSize, wave.Privacy = crosstab.MinCellSize, crosstab.Privacy
+		}
+
+		waves = append([]model.SeriesWave{wave}, waves...)
+		crosstabs = append([]*model.Crosstab{crosstab}, crosstabs...)
+	}
+
+	return model.NewSeriesResults(waves, crosstabs, dimension, group), nil
+}
+
+// demographics aggregates the votes of the survey, or of one of its choices, by the
+// demographics of the voters. The counts are perturbed if the owner chose a noise mechanism
+func (s *SurveyService) demographics(survey *model.Survey, choiceID uint) (rows []model.CrosstabRow, privacy *model.Privacy, err error) {
+	if survey.Noise == model.NoiseNone {
+		rows, err = s.crosstabRows(survey, choiceID)
+		return rows, nil, err
+	}
+
+	// noise only protects counts that do not change anymore
+	switch survey.Status(time.Now().UTC()) {
+	case model.SurveyStatusClosed, model.SurveyStatusArchived:
+	default:
+		return nil, nil, errors.New("results are available after the survey has ended")
+	}
+
+	tree, err := s.surveyRepo.FindByIDWithoutVotes(survey.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// every choice is needed to perturb the same way as the full crosstab
+	rows, err = s.crosstabRows(survey, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	release, err := s.privacyRelease(survey, model.ReleaseDemographics)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	n := newNoise(survey, release, demographicsSensitivity(tree))
+
+	return n.crosstabRows(tree, rows), n.privacy(release), nil
+}
+
+// crosstabRows aggregates the votes by the demographics of the voters, anonymous
+// surveys only have the demographic tallies taken when the ballots were cast
+func (s *SurveyService) crosstabRows(survey *model.Survey, choiceID uint) (rows []model.CrosstabRow, err error) {
+	if survey.Anonymous {
+		return s.surveyRepo.DemographicCrosstab(survey.ID, choiceID)
+	}
+
+	return s.surveyRepo.Crosstab(survey.ID, choiceID, survey.DateEnd)
+}
+
+// perturbResults replaces the votes of a survey with noisy counts if the owner chose
+// a noise mechanism
+func (s *SurveyService) perturbResults(survey *model.Survey) error {
+	if survey.Noise == model.NoiseNone {
+		return nil
+	}
+
+	release, err := s.privacyRelease(survey, model.ReleaseResults)
+	if err != nil {
+		return err
+	}
+
+	n := newNoise(survey, release, resultsSensitivity(survey))
+	n.results(survey)
+	survey.Privacy = n.privacy(release)
+
+	return nil
+}
+
+// privacyRelease returns the recorded release of the survey, the first time a
+// release is published it spends the survey epsilon from the privacy budget
+func (s *SurveyService) privacyRelease(survey *model.Survey, name model.Release) (release *model.PrivacyRelease, err error) {
+	releases, err := s.surveyRepo.ListPrivacyReleases(survey.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var spent float64
+	for i := range releases {
+		if releases[i].Release == name {
+			return &releases[i], nil
+		}
+		spent += releases[i].Epsilon
+	}
+
+	if spent+survey.Epsilon > survey.PrivacyBudget {
+		return nil, appError.ErrPrivacyBudget
+	}
+
+	release = &model.PrivacyRelease{SurveyRefer: survey.ID, Release: name, Epsilon: survey.Epsilon}
+	if err = binary.Read(rand.Reader, binary.BigEndian, &release.Seed); err != nil {
+		return nil, err
+	}
+
+	if err = s.surveyRepo.CreatePrivacyRelease(release); err != nil {
+		// published at the same time by another request
+		releases, listErr := s.surveyRepo.ListPrivacyReleases(survey.ID)
+		if listErr != nil {
+			return nil, listErr
+		}
+		for i := range releases {
+			if releases[i].Release == name {
+				return &releases[i], nil
+			}
+		}
+		return nil, err
+	}
+
+	return release, nil
+}
+
+// Vote casts the ballot of the user, private surveys also need an invitation token
+func (s *SurveyService) Vote(userID, surveyID uint, answers []model.Answer, token string) (created []model.Vote, receipt string, err error) {
+	return s.surveyRepo.Vote(userID, surveyID, answers, token, time.Now().UTC())
 }
 
 func (s *SurveyService) VotedAlready(userID, surveyID uint) (voted bool, err error) {
 	return s.surveyRepo.VotedAlready(userID, surveyID)
 }
 
+// SaveDraft keeps the partial answers of the user to an active survey
+func (s *SurveyService) SaveDraft(userID, surveyID uint, answers []model.Answer) (draft *model.DraftBallot, err error) {
+	reduced, err := s.activeDraftSurvey(surveyID)
+	if err != nil {
+		return nil, err
+	}
+	if !reduced.BallotChanges {
+		voted, err := s.surveyRepo.VotedAlready(userID, surveyID)
+		if err != nil {
+			return nil, err
+		}
+		if voted {
+			return nil, appError.ErrVotedAlready
+		}
+	}
+
+	survey, err := s.surveyRepo.FindByIDWithoutVotes(surveyID)
+	if err != nil {
+		return nil, err
+	}
+	if err = survey.ValidateDraftAnswers(answers); err != nil {
+		return nil, err
+	}
+
+	draft, err = model.NewDraftBallot(userID, surveyID, answers)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.surveyRepo.SaveDraft(draft); err != nil {
+		return nil, err
+	}
+	draft.ExpiresAt = reduced.DateEnd
+
+	return draft, nil
+}
+
+// FindDraft returns the draft ballot of the user, drafts expire when the survey closes
+func (s *SurveyService) FindDraft(userID, surveyID uint) (draft *model.DraftBallot, err error) {
+	reduced, err := s.activeDraftSurvey(surveyID)
+	if errors.Is(err, appError.ErrSurveyClosed) {
+		return nil, appError.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	draft, err = s.surveyRepo.FindDraft(userID, surveyID)
+	if err != nil {
+		return nil, err
+	}
+	draft.ExpiresAt = reduced.DateEnd
+
+	return draft, nil
+}
+
+// SubmitDraft casts the draft ballot of the user as a vote, the vote removes the draft
+func (s *SurveyService) SubmitDraft(userID, surveyID uint, token string) (created []model.Vote, receipt string, err error) {
+	draft, err := s.FindDraft(userID, surveyID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return s.Vote(userID, surveyID, draft.Answers, token)
+}
+
+// DeleteClosedDrafts removes the drafts of surveys that no longer accept ballots
+func (s *SurveyService) DeleteClosedDrafts() (deleted int64, err error) {
+	return s.surveyRepo.DeleteClosedDrafts(time.Now().UTC())
+}
+
+// activeDraftSurvey loads a survey that accepts drafts
+func (s *SurveyService) activeDraftSurvey(surveyID uint) (reduced *model.Survey, err error) {
+	reduced, err = s.surveyRepo.FindByIDReduced(surveyID)
+	if err != nil {
+		return nil, err
+	}
+	if reduced.ID == 0 {
+		return nil, appError.ErrNotFound
+	}
+	if reduced.Status(time.Now().UTC()) != model.SurveyStatusActive {
+		return nil, appError.ErrSurveyClosed
+	}
+
+	return reduced, nil
+}
+
+// MyBallot returns the current answers of the user to a survey
+func (s *SurveyService) MyBallot(userID, surveyID uint) (ballot *model.Ballot, err error) {
+	reduced, err := s.surveyRepo.FindByIDReduced(surveyID)
+	if err != nil {
+		return nil, err
+	}
+	if reduced.ID == 0 {
+		return nil, appError.ErrNotFound
+	}
+	if reduced.Anonymous {
+		return nil, appError.ErrAnonymousSurvey
+	}
+
+	votes, err := s.surveyRepo.ListBallotVotes(userID, surveyID)
+	if err != nil {
+		return nil, err
+	}
+	if len(votes) == 0 {
+		return nil, appError.ErrNotFound
+	}
+
+	replaced, err := s.surveyRepo.CountReplacedBallots(userID, surveyID)
+	if err != nil {
+		return nil, err
+	}
+
+	survey, err := s.surveyRepo.FindByIDWithoutVotes(surveyID)
+	if err != nil {
+		return nil, err
+	}
+
+	ballot = &model.Ballot{
+		SurveyID:   surveyID,
+		Revision:   replaced + 1,
+		Changeable: reduced.BallotChanges && reduced.Status(time.Now().UTC()) == model.SurveyStatusActive,
+		Answers:    survey.AnswersFrom(votes),
+	}
+	for _, vote := range votes {
+		if vote.CreatedAt.After(ballot.CastAt) {
+			ballot.CastAt = vote.CreatedAt
+		}
+	}
+
+	return ballot, nil
+}
+
 func (s *SurveyService) CountWaitingConfirmation() (count int, err error) {
 	return s.surveyRepo.CountWaitingConfirmation()
 }
 
-func (s *SurveyService) CountActive() (count int, err error) {
-	return s.surveyRepo.CountActive()
+// SummarizeWaitingConfirmation counts the surveys waiting longer than the review SLA
+// next to the waiting and claimed ones
+func (s *SurveyService) SummarizeWaitingConfirmation() (queue *model.ReviewQueue, err error) {
+	queue, err = s.surveyRepo.SummarizeWaitingConfirmation(time.Now().UTC().Add(-s.approvalPolicy.ReviewSLA))
+	if err != nil {
+		return nil, err
+	}
+	queue.SLAHours = int(s.approvalPolicy.ReviewSLA.Hours())
+
+	return queue, nil
+}
+
+// ReconcileTallies recounts the result tallies from the votes and repairs the ones
+// that drifted, tallies missing for older votes are filled in the same way
+func (s *SurveyService) ReconcileTallies() (drift []model.TallyDrift, err error) {
+	return s.surveyRepo.ReconcileTallies()
+}
+
+// Claim locks a waiting survey to the reviewer for the claim window so other
+// employees do not review it at the same time, claiming again extends the window
+func (s *SurveyService) Claim(reviewer *model.Employee, surveyID uint) (claimedUntil time.Time, err error) {
+	survey, err := s.FindByIDReduced(surveyID)
+	if err != nil {
+		return claimedUntil, err
+	}
+	if survey.ID == 0 {
+		return claimedUntil, appError.ErrNotFound
+	}
+	if survey.Status(time.Now().UTC()) != model.SurveyStatusWaiting {
+		return claimedUntil, errors.New("only surveys waiting for confirmation can be claimed")
+	}
+	if survey.UserRefer == reviewer.UserRefer {
+		return claimedUntil, errors.New("you can not review your own survey")
+	}
+
+	review, err := s.reviewProgress(survey, reviewer.UserRefer)
+	if err != nil {
+		return claimedUntil, err
+	}
+	if review == nil {
+		return claimedUntil, errors.New("you already reviewed this survey")
+	}
+
+	claimedUntil = time.Now().UTC().Add(s.approvalPolicy.ClaimWindow)
+	if err = s.surveyRepo.Claim(surveyID, reviewer.UserRefer, claimedUntil); err != nil {
+		return time.Time{}, err
+	}
+
+	return claimedUntil, nil
+}
+
+// Release gives up the claim of the reviewer on a survey
+func (s *SurveyService) Release(reviewer *model.Employee, surveyID uint) (err error) {
+	survey, err := s.FindByIDReduced(surveyID)
+	if err != nil {
+		return err
+	}
+	if survey.ID == 0 {
+		return appError.ErrNotFound
+	}
+	if survey.ClaimedBy == nil || *survey.ClaimedBy != reviewer.UserRefer {
+		return errors.New("you have not claimed this survey")
+	}
+
+	return s.surveyRepo.Release(surveyID, reviewer.UserRefer)
+}
+
+// MarkSensitive lets a reviewer flag a waiting survey as a sensitive topic, which then
+// needs a senior approval, owners can not set the flag themselves
+func (s *SurveyService) MarkSensitive(reviewer *model.Employee, surveyID uint, sensitive bool) (err error) {
+	survey, err := s.FindByIDReduced(surveyID)
+	if err != nil {
+		return err
+	}
+	if survey.ID == 0 {
+		return appError.ErrNotFound
+	}
+	if survey.Status(time.Now().UTC()) != model.SurveyStatusWaiting {
+		return errors.New("only surveys waiting for confirmation can be flagged")
+	}
+	if survey.UserRefer == reviewer.UserRefer {
+		return errors.New("you can not review your own survey")
+	}
+	if !sensitive && survey.Noise != model.NoiseNone {
+		return errors.New("a survey with a noise mechanism is always sensitive")
+	}
+
+	return s.surveyRepo.SetSensitive(surveyID, sensitive)
+}
+
+// estimateEligibleUsers attaches the eligibility criteria of a listed survey, counts
+// the registered users they admit when the survey starts and estimates its audience
+func (s *SurveyService) estimateEligibleUsers(survey *model.Survey) (err error) {
+	reduced, err := s.surveyRepo.FindByIDReduced(survey.ID)
+	if err != nil {
+		return err
+	}
+	survey.Eligibility = reduced.Eligibility
+	survey.RespondentCap = reduced.RespondentCap
+
+	survey.Review.EligibleUsers, err = s.surveyRepo.CountEligibleUsers(&survey.Eligibility, survey.DateStart)
+	if err != nil {
+		return err
+	}
+	survey.Audience = model.EstimateAudience(survey, survey.Review.EligibleUsers)
+
+	return nil
+}
+
+// CountActive counts the active surveys, only those the voter can vote in when given
+func (s *SurveyService) CountActive(voter *model.User) (count int, err error) {
+	return s.surveyRepo.CountActive(voter)
 }
 
 func (s *SurveyService) CountResults() (count int, err error) {
 	return s.surveyRepo.CountResults()
 }
 
-func (s *SurveyService) ListWaitingConfirmation(limit, offset uint) (survey []model.Survey, err error) {
-	return s.surveyRepo.ListWaitingConfirmation(limit, offset)
+// ListWaitingConfirmation lists the surveys the reviewer has not decided on yet with
+// their approval progress, earlier decisions are included to spot resubmissions
+func (s *SurveyService) ListWaitingConfirmation(reviewerID, limit, offset uint) (surveys []model.Survey, err error) {
+	surveys, err = s.surveyRepo.ListWaitingConfirmation(reviewerID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	surveyIDs := make([]uint, 0, len(surveys))
+	for _, survey := range surveys {
+		surveyIDs = append(surveyIDs, survey.ID)
+	}
+
+	moderations, err := s.surveyRepo.ListModerations(surveyIDs...)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	for i := range surveys {
+		if err = s.estimateEligibleUsers(&surveys[i]); err != nil {
+			return nil, err
+		}
+		s.approvalPolicy.Review(&surveys[i], surveys[i].Review, now)
+
+		for _, moderation := range moderations {
+			if moderation.SurveyRefer == surveys[i].ID {
+				surveys[i].Moderations = append(surveys[i].Moderations, moderation)
+			}
+		}
+	}
+
+	return surveys, nil
 }
 
-func (s *SurveyService) ListActive(limit, offset uint) (survey []model.Survey, err error) {
-	return s.surveyRepo.ListActive(limit, offset)
+// ListActive lists the active surveys, only those the voter can vote in when given
+func (s *SurveyService) ListActive(limit, offset uint, voter *model.User) (survey []model.Survey, err error) {
+	return s.surveyRepo.ListActive(limit, offset, voter)
 }
 
-func (s *SurveyService) ListResults(limit, offset uint) (survey []model.Survey, err error) {
-	return s.surveyRepo.ListResults(limit, offset)
+func (s *SurveyService) ListResults(limit, offset uint) (surveys []model.Survey, err error) {
+	surveys, err = s.surveyRepo.ListResults(limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range surveys {
+		err = s.perturbResults(&surveys[i])
+		if errors.Is(err, appError.ErrPrivacyBudget) {
+			// withhold the results instead of failing the whole list
+			surveys[i].Questions = make([]model.Question, 0)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return surveys, nil
 }
 
 func (s *SurveyService) FindByIDReduced(userId uint) (survey *model.Survey, err error) {
 	return s.surveyRepo.FindByIDReduced(userId)
 }
 
-func (s *SurveyService) FindByIDWithVotes(userId uint) (survey *model.Survey, err error) {
-	return s.surveyRepo.FindByIDWithVotes(userId)
+func (s *SurveyService) FindByIDWithResults(userId uint) (survey *model.Survey, err error) {
+	survey, err = s.surveyRepo.FindByIDWithResults(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.perturbResults(survey); err != nil {
+		return nil, err
+	}
+
+	return survey, nil
+}
+
+// FindByIDWithVotes returns the results of a finished survey along with its individual
+// votes, surveys publishing noisy counts never disclose their votes
+func (s *SurveyService) FindByIDWithVotes(surveyID uint) (survey *model.Survey, err error) {
+	reduced, err := s.surveyRepo.FindByIDReduced(surveyID)
+	if err != nil {
+		return nil, err
+	}
+	if reduced.ID == 0 {
+		return nil, appError.ErrNotFound
+	}
+
+	switch reduced.Status(time.Now().UTC()) {
+	case model.SurveyStatusClosed, model.SurveyStatusArchived:
+	default:
+		return nil, errors.New("results are available after the survey has ended")
+	}
+	if reduced.Noise != model.NoiseNone {
+		return nil, appError.ErrNoisyResults
+	}
+
+	survey, err = s.surveyRepo.FindByIDWithResults(surveyID)
+	if err != nil {
+		return nil, err
+	}
+
+	votes, err := s.surveyRepo.ListVotes(surveyID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, vote := range votes {
+		question := survey.Question(vote.QuestionRefer)
+		if question == nil {
+			continue
+		}
+		if vote.ChoiceRefer == nil {
+			question.Votes = append(question.Votes, vote)
+			continue
+		}
+		for i := range question.Choices {
+			if question.Choices[i].ID == *vote.ChoiceRefer {
+				question.Choices[i].Votes = append(question.Choices[i].Votes, vote)
+			}
+		}
+	}
+
+	return survey, nil
+}
+
+// BallotLog returns the verified ballot log of a finished survey and publishes its root
+// the first time, surveys publishing noisy counts never disclose their ballots
+func (s *SurveyService) BallotLog(surveyID uint) (log *model.BallotLog, err error) {
+	reduced, err := s.surveyRepo.FindByIDReduced(surveyID)
+	if err != nil {
+		return nil, err
+	}
+	if reduced.ID == 0 {
+		return nil, appError.ErrNotFound
+	}
+
+	switch reduced.Status(time.Now().UTC()) {
+	case model.SurveyStatusClosed, model.SurveyStatusArchived:
+	default:
+		return nil, errors.New("results are available after the survey has ended")
+	}
+	if reduced.Noise != model.NoiseNone {
+		return nil, appError.ErrNoisyResults
+	}
+
+	return s.verifiedBallotLog(reduced)
+}
+
+// PublishBallotRoots publishes the ballot roots of the surveys that closed since the
+// last run, the roots are fixed from then on
+func (s *SurveyService) PublishBallotRoots() (roots map[uint]string, err error) {
+	surveyIDs, err := s.surveyRepo.ListUnpublishedBallotRoots()
+	if err != nil {
+		return nil, err
+	}
+
+	roots = make(map[uint]string)
+	for _, surveyID := range surveyIDs {
+		reduced, err := s.surveyRepo.FindByIDReduced(surveyID)
+		if err != nil {
+			return roots, err
+		}
+
+		log, err := s.verifiedBallotLog(reduced)
+		if err != nil {
+			return roots, err
+		}
+		roots[surveyID] = log.Root
+	}
+
+	return roots, nil
+}
+
+// verifiedBallotLog reads and verifies the ballot log of a closed survey against its
+// published root, publishing the root when it is not yet
+func (s *SurveyService) verifiedBallotLog(reduced *model.Survey) (log *model.BallotLog, err error) {
+	// anonymous ballots join the log once voting closed
+	if err = s.surveyRepo.ChainPendingBallots(reduced.ID); err != nil {
+		return nil, err
+	}
+
+	entries, err := s.surveyRepo.ListBallotLog(reduced.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	log = &model.BallotLog{SurveyID: reduced.ID, Entries: entries}
+	root, err := log.Verify()
+	if err != nil {
+		return nil, fmt.Errorf("ballot log of survey %d: %w", reduced.ID, err)
+	}
+	if reduced.BallotRoot != "" && reduced.BallotRoot != root {
+		return nil, fmt.Errorf("ballot log of survey %d does not match its published root", reduced.ID)
+	}
+	if reduced.BallotRoot == "" {
+		if err = s.surveyRepo.PublishBallotRoot(reduced.ID, root); err != nil {
+			return nil, err
+		}
+	}
+	log.Root = root
+
+	return log, nil
+}
+
+// CheckReceipt tells whether the ballot of a receipt is in the log of its survey
+func (s *SurveyService) CheckReceipt(receipt string) (check *model.ReceiptCheck, err error) {
+	entry, replaced, err := s.surveyRepo.FindBallotLogEntry(receipt)
+	if err != nil {
+		return nil, err
+	}
+
+	reduced, err := s.surveyRepo.FindByIDReduced(entry.SurveyRefer)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.ReceiptCheck{
+		SurveyID: entry.SurveyRefer,
+		Sequence: entry.Sequence,
+		Included: true,
+		Replaced: replaced,
+		Root:     reduced.BallotRoot,
+	}, nil
+}
+
+// ExportResults writes the choice counts of a finished survey in the given format,
+// noisy surveys export their perturbed counts
+func (s *SurveyService) ExportResults(surveyID uint, format model.ExportFormat, w io.Writer) (err error) {
+	if err = format.Validate(); err != nil {
+		return err
+	}
+	if _, err = s.finished(surveyID); err != nil {
+		return err
+	}
+
+	survey, err := s.FindByIDWithResults(surveyID)
+	if err != nil {
+		return err
+	}
+
+	table := helpers.NewTableWriter(format, w, model.ResultColumns)
+	for _, row := range model.ResultRows(survey) {
+		if err = table.Write(row); err != nil {
+			return err
+		}
+	}
+
+	return table.Close()
+}
+
+// ExportResponses writes one row per respondent of a finished survey in the given
+// format. The votes are streamed from the store, demographics of respondents in groups
+// smaller than the minimum cell size are suppressed and noisy surveys never disclose
+// their ballots
+func (s *SurveyService) ExportResponses(surveyID uint, format model.ExportFormat, w io.Writer) (err error) {
+	return s.exportResponses(surveyID, format, w, false)
+}
+
+// ExportCodedResponses writes the responses of a finished survey coded after its
+// codebook, for statistics packages
+func (s *SurveyService) ExportCodedResponses(surveyID uint, format model.ExportFormat, w io.Writer) (err error) {
+	return s.exportResponses(surveyID, format, w, true)
+}
+
+// Codebook describes the variables of the coded response export of a survey
+func (s *SurveyService) Codebook(surveyID uint) (codebook *model.Codebook, err error) {
+	reduced, err := s.surveyRepo.FindByIDReduced(surveyID)
+	if err != nil {
+		return nil, err
+	}
+	if reduced.ID == 0 {
+		return nil, appError.ErrNotFound
+	}
+
+	survey, err := s.surveyRepo.FindByIDWithoutVotes(surveyID)
+	if err != nil {
+		return nil, err
+	}
+	survey.Anonymous = reduced.Anonymous
+
+	return model.NewCodebook(survey, s.privacyPolicy.CellSize(reduced)), nil
+}
+
+func (s *SurveyService) exportResponses(surveyID uint, format model.ExportFormat, w io.Writer, coded bool) (err error) {
+	if err = format.Validate(); err != nil {
+		return err
+	}
+	reduced, err := s.finished(surveyID)
+	if err != nil {
+		return err
+	}
+	if reduced.Noise != model.NoiseNone {
+		return appError.ErrNoisyResults
+	}
+
+	// question and choice texts
+	survey, err := s.surveyRepo.FindByIDWithoutVotes(surveyID)
+	if err != nil {
+		return err
+	}
+	survey.Anonymous = reduced.Anonymous
+
+	matrix := model.NewResponseMatrix(survey, s.privacyPolicy.CellSize(reduced))
+	if coded {
+		matrix = model.NewCodedResponseMatrix(survey, s.privacyPolicy.CellSize(reduced))
+	}
+	if !survey.Anonymous {
+		err = s.surveyRepo.StreamRespondents(surveyID, func(user *model.User) error {
+			matrix.CountRespondent(user)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	table := helpers.NewTableWriter(format, w, matrix.Columns())
+	err = s.surveyRepo.StreamResponses(surveyID, func(row *model.ResponseRow) error {
+		if complete := matrix.Add(*row); complete != nil {
+			return table.Write(complete)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if complete := matrix.Flush(); complete != nil {
+		if err = table.Write(complete); err != nil {
+			return err
+		}
+	}
+
+	return table.Close()
+}
+
+// finished loads a survey whose results are available
+func (s *SurveyService) finished(surveyID uint) (survey *model.Survey, err error) {
+	survey, err = s.surveyRepo.FindByIDReduced(surveyID)
+	if err != nil {
+		return nil, err
+	}
+	if survey.ID == 0 {
+		return nil, appError.ErrNotFound
+	}
+
+	switch survey.Status(time.Now().UTC()) {
+	case model.SurveyStatusClosed, model.SurveyStatusArchived:
+	default:
+		return nil, errors.New("results are available after the survey has ended")
+	}
+
+	return survey, nil
 }
 
 func (s *SurveyService) FindByIDWithoutVotes(userId uint) (survey *model.Survey, err error) {
@@ -98,5 +1095,413 @@ func (s *SurveyService) CountQuestion(id uint) (count int, err error) {
 }
 
 func (s *SurveyService) Create(create *model.Survey) (survey *model.Survey, err error) {
+	create.ResetManaged()
+	if err = create.Validate(); err != nil {
+		return nil, err
+	}
+
+	// new surveys are drafts until the owner submits them for review
+	create.ConfirmStatus = model.ConfirmStatusDraft
+	// waves are linked once both surveys are known to be owned by the user
+	create.PreviousWave = nil
+
 	return s.surveyRepo.CreateSurvey(create)
 }
+
+// Import creates a draft survey of the user from a survey definition document
+func (s *SurveyService) Import(userID uint, definition *model.SurveyDefinition) (survey *model.Survey, err error) {
+	create, err := definition.Survey()
+	if err != nil {
+		return nil, err
+	}
+	create.UserRefer = userID
+
+	return s.Create(create)
+}
+
+// ExportDefinition returns the definition document of a survey to its owner
+func (s *SurveyService) ExportDefinition(userID, surveyID uint) (definition *model.SurveyDefinition, err error) {
+	survey, err := s.FindOwned(userID, surveyID)
+	if err != nil {
+		return nil, err
+	}
+
+	return model.NewSurveyDefinition(survey), nil
+}
+
+// Clone copies the structure of a survey of the user into a new draft with new dates,
+// votes, invitations and the review state are left behind. A copy starting later
+// becomes the next wave of the series of the survey
+func (s *SurveyService) Clone(userID, surveyID uint, request model.CloneRequest) (survey *model.Survey, err error) {
+	definition, err := s.ExportDefinition(userID, surveyID)
+	if err != nil {
+		return nil, err
+	}
+
+	survey, err = s.Import(userID, definition.Scheduled(request))
+	if err != nil {
+		return nil, err
+	}
+
+	if definition.DateStart.Before(request.DateStart) {
+		if err = s.surveyRepo.SetPreviousWave(survey.ID, &surveyID); err != nil {
+			return nil, err
+		}
+		survey.PreviousWave = &surveyID
+	}
+
+	return survey, nil
+}
+
+// LinkWave makes a survey of the user the next wave of another of their surveys,
+// waves start one after the other so the series can not loop
+func (s *SurveyService) LinkWave(userID, surveyID, previousID uint) (err error) {
+	survey, err := s.owned(userID, surveyID)
+	if err != nil {
+		return err
+	}
+	previous, err := s.owned(userID, previousID)
+	if err != nil {
+		return err
+	}
+
+	if !previous.DateStart.Before(survey.DateStart) {
+		return errors.New("the previous wave has to start before the survey")
+	}
+
+	return s.surveyRepo.SetPreviousWave(surveyID, &previousID)
+}
+
+// UnlinkWave removes a survey of the user from the series of its earlier waves
+func (s *SurveyService) UnlinkWave(userID, surveyID uint) (err error) {
+	if _, err = s.owned(userID, surveyID); err != nil {
+		return err
+	}
+
+	return s.surveyRepo.SetPreviousWave(surveyID, nil)
+}
+
+// SaveTemplate keeps the structure of a survey of the user as a personal template
+func (s *SurveyService) SaveTemplate(userID, surveyID uint, request model.TemplateRequest) (template *model.SurveyTemplate, err error) {
+	definition, err := s.ExportDefinition(userID, surveyID)
+	if err != nil {
+		return nil, err
+	}
+
+	template, err = model.NewSurveyTemplate(request.Name, definition)
+	if err != nil {
+		return nil, err
+	}
+	template.UserRefer = &userID
+
+	if err = s.surveyRepo.CreateTemplate(template); err != nil {
+		return nil, err
+	}
+
+	return template, nil
+}
+
+// PublishTemplate publishes the structure of a confirmed public survey as an organization template
+func (s *SurveyService) PublishTemplate(employee *model.Employee, surveyID uint, request model.TemplateRequest) (template *model.SurveyTemplate, err error) {
+	reduced, err := s.surveyRepo.FindByIDReduced(surveyID)
+	if err != nil {
+		return nil, err
+	}
+	if reduced.ID == 0 {
+		return nil, appError.ErrNotFound
+	}
+	if err = reduced.ValidateOrganizationTemplate(); err != nil {
+		return nil, err
+	}
+
+	survey, err := s.structure(reduced)
+	if err != nil {
+		return nil, err
+	}
+
+	template, err = model.NewSurveyTemplate(request.Name, model.NewSurveyDefinition(survey))
+	if err != nil {
+		return nil, err
+	}
+	template.Organization = true
+	template.PublishedBy = &employee.ID
+
+	if err = s.surveyRepo.CreateTemplate(template); err != nil {
+		return nil, err
+	}
+
+	return template, nil
+}
+
+// ListTemplates lists the organization templates and the personal templates of the user
+func (s *SurveyService) ListTemplates(userID uint) (templates []model.SurveyTemplate, err error) {
+	return s.surveyRepo.ListTemplates(userID)
+}
+
+// FindTemplate returns a template with its definition, personal templates of other
+// users are not found
+func (s *SurveyService) FindTemplate(userID, templateID uint) (template *model.SurveyTemplate, err error) {
+	template, err = s.surveyRepo.FindTemplate(templateID)
+	if err != nil {
+		return nil, err
+	}
+	if !template.Organization && (template.UserRefer == nil || *template.UserRefer != userID) {
+		return nil, appError.ErrNotFound
+	}
+
+	return template, nil
+}
+
+// UseTemplate starts a new draft of the user from a template with new dates
+func (s *SurveyService) UseTemplate(userID, templateID uint, request model.CloneRequest) (survey *model.Survey, err error) {
+	template, err := s.FindTemplate(userID, templateID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Import(userID, template.Definition.Scheduled(request))
+}
+
+// DeleteTemplate removes a personal template of the user
+func (s *SurveyService) DeleteTemplate(userID, templateID uint) (err error) {
+	template, err := s.FindTemplate(userID, templateID)
+	if err != nil {
+		return err
+	}
+	if template.Organization {
+		return appError.ErrNotTemplateOwner
+	}
+
+	return s.surveyRepo.DeleteTemplate(templateID)
+}
+
+// UnpublishTemplate removes an organization template
+func (s *SurveyService) UnpublishTemplate(templateID uint) (err error) {
+	template, err := s.surveyRepo.FindTemplate(templateID)
+	if err != nil {
+		return err
+	}
+	if !template.Organization {
+		return appError.ErrNotFound
+	}
+
+	return s.surveyRepo.DeleteTemplate(templateID)
+}
+
+// owned loads the survey and checks that it was created by the user
+func (s *SurveyService) owned(userID, surveyID uint) (survey *model.Survey, err error) {
+	survey, err = s.surveyRepo.FindByIDReduced(surveyID)
+	if err != nil {
+		return nil, err
+	}
+	if survey.ID == 0 {
+		return nil, appError.ErrNotFound
+	}
+	if survey.UserRefer != userID {
+		return nil, appError.ErrNotOwner
+	}
+
+	return survey, nil
+}
+
+// editable loads an owned survey that can still be structurally changed
+func (s *SurveyService) editable(userID, surveyID uint) (survey *model.Survey, err error) {
+	survey, err = s.owned(userID, surveyID)
+	if err != nil {
+		return nil, err
+	}
+	if !canTransition(survey.Status(time.Now().UTC()), model.ConfirmStatusDraft) {
+		return nil, appError.ErrSurveyLocked
+	}
+
+	return survey, nil
+}
+
+// CheckOwner checks that the survey was created by the user
+func (s *SurveyService) CheckOwner(userID, surveyID uint) (err error) {
+	_, err = s.owned(userID, surveyID)
+	return err
+}
+
+func (s *SurveyService) FindOwned(userID, surveyID uint) (survey *model.Survey, err error) {
+	reduced, err := s.owned(userID, surveyID)
+	if err != nil {
+		return nil, err
+	}
+
+	survey, err = s.structure(reduced)
+	if err != nil {
+		return nil, err
+	}
+
+	survey.Moderations, err = s.surveyRepo.ListModerations(surveyID)
+	if err != nil {
+		return nil, err
+	}
+
+	return survey, nil
+}
+
+// structure loads the questions, settings and quotas of a survey
+func (s *SurveyService) structure(reduced *model.Survey) (survey *model.Survey, err error) {
+	surveyID := reduced.ID
+	survey, err = s.surveyRepo.FindByIDWithoutVotes(surveyID)
+	if err != nil {
+		return nil, err
+	}
+	survey.ConfirmStatus = reduced.ConfirmStatus
+	survey.Sensitive = reduced.Sensitive
+	survey.Audience = reduced.Audience
+	survey.MinCellSize = reduced.MinCellSize
+	survey.Noise = reduced.Noise
+	survey.PrivacyBudget = reduced.PrivacyBudget
+	survey.Epsilon = reduced.Epsilon
+	survey.BallotChanges = reduced.BallotChanges
+	survey.Anonymous = reduced.Anonymous
+	survey.Private = reduced.Private
+	survey.Eligibility = reduced.Eligibility
+	survey.RespondentCap = reduced.RespondentCap
+	survey.Respondents = reduced.Respondents
+	survey.QuotasMetAt = reduced.QuotasMetAt
+	survey.PreviousWave = reduced.PreviousWave
+
+	survey.Quotas, err = s.surveyRepo.ListQuotas(surveyID)
+	if err != nil {
+		return nil, err
+	}
+
+	return survey, nil
+}
+
+// QuotaStatus returns the fill status of the quotas to the owner of the survey
+func (s *SurveyService) QuotaStatus(userID, surveyID uint) (status *model.QuotaStatus, err error) {
+	reduced, err := s.owned(userID, surveyID)
+	if err != nil {
+		return nil, err
+	}
+
+	quotas, err := s.surveyRepo.ListQuotas(surveyID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.QuotaStatus{
+		RespondentCap: reduced.RespondentCap,
+		Respondents:   reduced.Respondents,
+		Quotas:        quotas,
+		Met:           model.QuotasMet(reduced.RespondentCap, reduced.Respondents, quotas),
+		MetAt:         reduced.QuotasMetAt,
+	}, nil
+}
+
+// CreateInvitations creates a batch of invitations to a private survey of the owner
+func (s *SurveyService) CreateInvitations(userID, surveyID uint, request model.InvitationRequest) (invitations []model.Invitation, err error) {
+	survey, err := s.owned(userID, surveyID)
+	if err != nil {
+		return nil, err
+	}
+	if !survey.Private {
+		return nil, errors.New("invitations can only be created for private surveys")
+	}
+
+	invitations, err = model.NewInvitations(surveyID, request)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.surveyRepo.CreateInvitations(invitations); err != nil {
+		return nil, err
+	}
+
+	return invitations, nil
+}
+
+// ListInvitations returns the invitations of a survey with their uses to its owner
+func (s *SurveyService) ListInvitations(userID, surveyID uint) (invitations []model.Invitation, err error) {
+	if _, err = s.owned(userID, surveyID); err != nil {
+		return nil, err
+	}
+
+	return s.surveyRepo.ListInvitations(surveyID)
+}
+
+// RevokeInvitation stops an invitation of the owner from admitting more voters,
+// ballots cast with it are kept
+func (s *SurveyService) RevokeInvitation(userID, surveyID, invitationID uint) (err error) {
+	if _, err = s.owned(userID, surveyID); err != nil {
+		return err
+	}
+
+	return s.surveyRepo.RevokeInvitation(surveyID, invitationID, time.Now().UTC())
+}
+
+// Update changes the subject, description or dates, any edit returns the survey to draft
+// in the same transaction
+func (s *SurveyService) Update(userID, surveyID uint, update model.Survey) (err error) {
+	survey, err := s.editable(userID, surveyID)
+	if err != nil {
+		return err
+	}
+
+	if err = update.ValidateUpdate(survey); err != nil {
+		return err
+	}
+
+	records := make([]interface{}, 0)
+	record, err := transitionRecord(userID, survey, model.ConfirmStatusDraft)
+	if err != nil {
+		return err
+	}
+	if record != nil {
+		records = append(records, record)
+	}
+
+	return s.surveyRepo.UpdateDraft(surveyID, survey.ConfirmStatus, update, records...)
+}
+
+// Replace swaps the whole survey including its questions and choices
+func (s *SurveyService) Replace(userID, surveyID uint, replace *model.Survey) (survey *model.Survey, err error) {
+	survey, err = s.editable(userID, surveyID)
+	if err != nil {
+		return nil, err
+	}
+
+	replace.ResetManaged()
+	// a topic flagged by a reviewer stays sensitive
+	replace.Sensitive = replace.Sensitive || survey.Sensitive
+	if err = replace.Validate(); err != nil {
+		return nil, err
+	}
+
+	records := make([]interface{}, 0)
+	record, err := transitionRecord(userID, survey, model.ConfirmStatusDraft)
+	if err != nil {
+		return nil, err
+	}
+	if record != nil {
+		records = append(records, record)
+	}
+
+	replace.UserRefer = userID
+	replace.ConfirmStatus = model.ConfirmStatusDraft
+
+	return s.surveyRepo.ReplaceDraft(surveyID, survey.ConfirmStatus, replace, records...)
+}
+
+func (s *SurveyService) Remove(userID, surveyID uint) (err error) {
+	if _, err = s.editable(userID, surveyID); err != nil {
+		return err
+	}
+
+	return s.surveyRepo.RemoveByID(surveyID)
+}
+
+// Submit sends a draft survey to the employees for confirmation
+func (s *SurveyService) Submit(userID, surveyID uint) (err error) {
+	survey, err := s.owned(userID, surveyID)
+	if err != nil {
+		return err
+	}
+
+	return s.transition(userID, survey, model.ConfirmStatusWaiting)
+}
@@ -1 +1,39 @@
 package service
+
+import (
+	"dou-survey/app/model"
+	"dou-survey/app/repository"
+	"dou-survey/internal/logger"
+	"dou-survey/internal/storage"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPublishTemplateOfAnotherUsersDraft(t *testing.T) {
+	os.Setenv("DB_CONNECTION_STRING", filepath.Join(t.TempDir(), "survey.db"))
+	apiLogger := logger.NewAPILogger()
+	apiLogger.InitLogger()
+	db := storage.InitializeDB(apiLogger)
+	repo := repository.NewSurveyRepository(db, apiLogger)
+	s := NewSurveyService(repo)
+
+	now := time.Now().UTC()
+	draft, err := s.Create(&model.Survey{UserRefer: 7, Subject: "s", Description: "d", DateStart: now.Add(24 * time.Hour), DateEnd: now.Add(48 * time.Hour),
+		Questions: []model.Question{{Value: "q", Choices: []model.Choice{{Value: "a"}, {Value: "b"}}}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	employee := &model.Employee{UserRefer: 2}
+	employee.ID = 1
+	if _, err = s.PublishTemplate(employee, draft.ID, model.TemplateRequest{Name: "copy"}); err == nil {
+		t.Error("PublishTemplate() published the draft of another user")
+	}
+
+	templates, err := s.ListTemplates(2)
+	if err != nil || len(templates) != 0 {
+		t.Errorf("ListTemplates() = %v, %v, want no templates", templates, err)
+	}
+}
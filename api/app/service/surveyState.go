@@ -0,0 +1,71 @@
+package service
+
+import (
+	appError "dou-survey/app/error"
+	"dou-survey/app/model"
+	"time"
+)
+
+// surveyTransitions lists the stored statuses a survey may be moved to from each
+// lifecycle status. Scheduled, active and closed follow from the survey dates.
+var surveyTransitions = map[model.SurveyStatus][]model.ConfirmStatus{
+	model.SurveyStatusDraft:    {model.ConfirmStatusDraft, model.ConfirmStatusWaiting},
+	model.SurveyStatusWaiting:  {model.ConfirmStatusDraft, model.ConfirmStatusAccepted, model.ConfirmStatusDeclined},
+	model.SurveyStatusDeclined: {model.ConfirmStatusDraft},
+	model.SurveyStatusClosed:   {model.ConfirmStatusArchived},
+}
+
+// canTransition reports whether a survey in the given status may be set to the stored status
+func canTransition(from model.SurveyStatus, to model.ConfirmStatus) bool {
+	for _, allowed := range surveyTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+
+	return false
+}
+
+// transition moves the survey to the stored status and records who did it,
+// the extra records are created in the same transaction
+func (s *SurveyService) transition(actorID uint, survey *model.Survey, to model.ConfirmStatus, records ...interface{}) error {
+	record, err := transitionRecord(actorID, survey, to)
+	if err != nil || record == nil {
+		return err
+	}
+
+	records = append([]interface{}{record}, records...)
+
+	if err := s.surveyRepo.Transition(survey.ID, survey.ConfirmStatus, to, records...); err != nil {
+		return err
+	}
+
+	survey.ConfirmStatus = to
+
+	return nil
+}
+
+// transitionRecord checks that the survey may be moved to the stored status and
+// returns the record of the change, nil if the status stays the same
+func transitionRecord(actorID uint, survey *model.Survey, to model.ConfirmStatus) (record *model.SurveyTransition, err error) {
+	now := time.Now().UTC()
+	from := survey.Status(now)
+
+	if !canTransition(from, to) {
+		return nil, &appError.TransitionError{From: string(from), To: string(to)}
+	}
+	if survey.ConfirmStatus == to {
+		// editing a draft keeps it a draft, nothing to record
+		return nil, nil
+	}
+
+	next := *survey
+	next.ConfirmStatus = to
+
+	return &model.SurveyTransition{
+		SurveyRefer: survey.ID,
+		ActorRefer:  actorID,
+		From:        from,
+		To:          next.Status(now),
+	}, nil
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"dou-survey/app/model"
+	"testing"
+	"time"
+)
+
+func TestSurveyStatus(t *testing.T) {
+	now := time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC)
+	start := now.AddDate(0, 0, -1)
+	end := now.AddDate(0, 0, 1)
+
+	tests := []struct {
+		name   string
+		survey model.Survey
+		want   model.SurveyStatus
+	}{
+		{"draft", model.Survey{ConfirmStatus: model.ConfirmStatusDraft}, model.SurveyStatusDraft},
+		{"waiting", model.Survey{ConfirmStatus: model.ConfirmStatusWaiting}, model.SurveyStatusWaiting},
+		{"scheduled", model.Survey{ConfirmStatus: model.ConfirmStatusAccepted, DateStart: end, DateEnd: end.AddDate(0, 0, 1)}, model.SurveyStatusScheduled},
+		{"active", model.Survey{ConfirmStatus: model.ConfirmStatusAccepted, DateStart: start, DateEnd: end}, model.SurveyStatusActive},
+		{"closed", model.Survey{ConfirmStatus: model.ConfirmStatusAccepted, DateStart: start.AddDate(0, 0, -1), DateEnd: start}, model.SurveyStatusClosed},
+		{"archived", model.Survey{ConfirmStatus: model.ConfirmStatusArchived}, model.SurveyStatusArchived},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.survey.Status(now); got != tt.want {
+				t.Errorf("Status() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		from model.SurveyStatus
+		to   model.ConfirmStatus
+		want bool
+	}{
+		{model.SurveyStatusDraft, model.ConfirmStatusWaiting, true},
+		{model.SurveyStatusDraft, model.ConfirmStatusAccepted, false},
+		{model.SurveyStatusWaiting, model.ConfirmStatusAccepted, true},
+		{model.SurveyStatusWaiting, model.ConfirmStatusDeclined, true},
+		{model.SurveyStatusActive, model.ConfirmStatusWaiting, false},
+		{model.SurveyStatusScheduled, model.ConfirmStatusDraft, false},
+		{model.SurveyStatusActive, model.ConfirmStatusArchived, false},
+		{model.SurveyStatusClosed, model.ConfirmStatusArchived, true},
+		{model.SurveyStatusArchived, model.ConfirmStatusAccepted, false},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.from)+"->"+string(tt.to), func(t *testing.T) {
+			if got := canTransition(tt.from, tt.to); got != tt.want {
+				t.Errorf("canTransition() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+// Command verifyballots recomputes the root of a ballot log exported from
+// GET /v1/surveys/ballot-log/:survey and recounts its choices, without access to
+// the database.
+//
+//	verifyballots -log ballot-log.json -root <published root>
+package main
+
+import (
+	"dou-survey/app/model"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+func main() {
+	var path, root string
+	flag.StringVar(&path, "log", "-", "Exported ballot log, - reads standard input")
+	flag.StringVar(&root, "root", "", "Published root to compare with, defaults to the root of the export")
+	flag.Parse()
+
+	if err := verify(path, root, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func verify(path, root string, out io.Writer) error {
+	in := os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		in = file
+	}
+
+	var log model.BallotLog
+	if err := json.NewDecoder(in).Decode(&log); err != nil {
+		return err
+	}
+	if root == "" {
+		root = log.Root
+	}
+
+	computed, err := log.Verify()
+	if err != nil {
+		return err
+	}
+	if computed != root {
+		return fmt.Errorf("computed root %s does not match %s", computed, root)
+	}
+
+	counts, err := log.CountChoices()
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "survey %d: %d ballots, root %s\n", log.SurveyID, len(log.Entries), computed)
+
+	choices := make([]uint, 0, len(counts))
+	for choiceID := range counts {
+		choices = append(choices, choiceID)
+	}
+	sort.Slice(choices, func(a, b int) bool { return choices[a] < choices[b] })
+	for _, choiceID := range choices {
+		fmt.Fprintf(out, "choice %d: %d\n", choiceID, counts[choiceID])
+	}
+
+	return nil
+}
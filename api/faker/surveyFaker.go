@@ -64,11 +64,14 @@ func (r *SurveyFaker) Generate(amount int) (err error) {
 }
 
 func removeDuplicateVotes(votes []model.Vote) []model.Vote {
-	allUsers := make(map[int]bool)
+	allUsers := make(map[uint]bool)
 	list := make([]model.Vote, 0)
 	for _, item := range votes {
-		if _, value := allUsers[int(item.UserRefer)]; !value {
-			allUsers[int(item.UserRefer)] = true
+		if item.UserRefer == nil {
+			continue
+		}
+		if _, value := allUsers[*item.UserRefer]; !value {
+			allUsers[*item.UserRefer] = true
 			list = append(list, item)
 		}
 	}
@@ -80,6 +80,9 @@ func Setup(db *storage.DbStore, dbCache *storage.DbCache, logger logger.Logger)
 		surveyRepo := dic.InitSurveyRepository(db, logger)
 		surveyService := dic.InitSurveyService(surveyRepo)
 		surveyController := dic.InitSurveyController(surveyService, userService, logger)
+		go reconcileTallies(surveyService, logger)
+		go publishBallotRoots(surveyService, logger)
+		go deleteClosedDrafts(surveyService, logger)
 
 		// single survey
 		survey := v1.Group("/survey")
@@ -96,8 +99,7 @@ func Setup(db *storage.DbStore, dbCache *storage.DbCache, logger logger.Logger)
 		{
 			routev1.SetupSurveysRoute(surveys, surveyController)
 
-			surveysAdmin := surveys.Group("admin")
-			routev1.SetupAdminSurveysRoute(surveysAdmin, surveyController)
+			// the review queue is only served under /admin, never without the employee check
 		}
 
 		// employees
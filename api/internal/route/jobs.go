@@ -0,0 +1,82 @@
+package route
+
+import (
+	"dou-survey/app/service"
+	"dou-survey/internal/logger"
+	"os"
+	"strconv"
+	"time"
+)
+
+// reconcileTallies recounts the result tallies at startup and then every
+// TALLY_RECONCILE_MINUTES, 0 disables the job
+func reconcileTallies(surveyService service.SurveyServiceInterface, logger logger.Logger) {
+	minutes, err := strconv.Atoi(os.Getenv("TALLY_RECONCILE_MINUTES"))
+	if err != nil {
+		minutes = 60
+	}
+	if minutes <= 0 {
+		return
+	}
+
+	for {
+		drift, err := surveyService.ReconcileTallies()
+		if err != nil {
+			logger.Error(err.Error())
+		} else if len(drift) > 0 {
+			logger.Warnf("repaired %d drifted tallies", len(drift))
+			for _, d := range drift {
+				logger.Warnf("tally of question %d choice %d was %+v, counted %+v", d.Counted.QuestionRefer, d.Counted.ChoiceRefer, d.Stored, d.Counted)
+			}
+		}
+
+		time.Sleep(time.Duration(minutes) * time.Minute)
+	}
+}
+
+// publishBallotRoots publishes the ballot roots of closed surveys every
+// BALLOT_ROOT_MINUTES, 0 disables the job
+func publishBallotRoots(surveyService service.SurveyServiceInterface, logger logger.Logger) {
+	minutes, err := strconv.Atoi(os.Getenv("BALLOT_ROOT_MINUTES"))
+	if err != nil {
+		minutes = 5
+	}
+	if minutes <= 0 {
+		return
+	}
+
+	for {
+		roots, err := surveyService.PublishBallotRoots()
+		if err != nil {
+			logger.Error(err.Error())
+		}
+		for surveyID, root := range roots {
+			logger.Infof("published ballot root %s of survey %d", root, surveyID)
+		}
+
+		time.Sleep(time.Duration(minutes) * time.Minute)
+	}
+}
+
+// deleteClosedDrafts removes the draft ballots of closed surveys every
+// DRAFT_CLEANUP_MINUTES, 0 disables the job
+func deleteClosedDrafts(surveyService service.SurveyServiceInterface, logger logger.Logger) {
+	minutes, err := strconv.Atoi(os.Getenv("DRAFT_CLEANUP_MINUTES"))
+	if err != nil {
+		minutes = 60
+	}
+	if minutes <= 0 {
+		return
+	}
+
+	for {
+		deleted, err := surveyService.DeleteClosedDrafts()
+		if err != nil {
+			logger.Error(err.Error())
+		} else if deleted > 0 {
+			logger.Infof("deleted %d expired draft ballots", deleted)
+		}
+
+		time.Sleep(time.Duration(minutes) * time.Minute)
+	}
+}
@@ -6,10 +6,17 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-// Surveys route that does not require auth
+// Survey route for employees, only mounted behind the auth and employee middlewares
 func SetupAdminSurveyRoute(surveys *gin.RouterGroup, c controller.SurveyControllerInterface) *gin.RouterGroup {
 	surveys.GET("/confirm/:survey", c.GetConfirmed)
 	surveys.POST("/confirm", c.Confirm)
+	surveys.GET("/history/:survey", c.History)
+	surveys.GET("/export/:survey/results", c.ExportResults)
+	surveys.GET("/export/:survey/responses", c.ExportResponses)
+	surveys.GET("/export/:survey/coded", c.ExportCodedResponses)
+	surveys.GET("/export/:survey/codebook", c.Codebook)
+	surveys.POST("/template/:survey", c.PublishTemplate)
+	surveys.DELETE("/template/:template", c.UnpublishTemplate)
 
 	return surveys
 }
@@ -6,10 +6,15 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-// Surveys route that does not require auth
+// Surveys route for employees, only mounted behind the auth and employee middlewares
 func SetupAdminSurveysRoute(surveys *gin.RouterGroup, c controller.SurveyControllerInterface) *gin.RouterGroup {
 	surveys.GET("/count/waiting", c.CountWaitingConfirmation)
+	surveys.GET("/summary/waiting", c.SummarizeWaitingConfirmation)
 	surveys.GET("/list/waiting", c.ListWaitingConfirmation)
+	surveys.POST("/claim", c.Claim)
+	surveys.POST("/release", c.Release)
+	surveys.POST("/sensitive", c.MarkSensitive)
+	surveys.POST("/tallies/reconcile", c.ReconcileTallies)
 
 	return surveys
 }
@@ -9,7 +9,37 @@ import (
 // Survey route that requires auth
 func SetupSurveyRoute(survey *gin.RouterGroup, c controller.SurveyControllerInterface) *gin.RouterGroup {
 	survey.POST("/create", c.Create)
+	survey.POST("/import", c.Import)
 	survey.POST("/vote", c.Vote)
+	survey.POST("/submit", c.Submit)
+	survey.GET("/list/votable", c.ListVotable)
+	survey.GET("/count/votable", c.CountVotable)
+	survey.GET("/templates", c.ListTemplates)
+	survey.GET("/templates/:template", c.Template)
+	survey.POST("/templates/:template/use", c.UseTemplate)
+	survey.DELETE("/templates/:template", c.DeleteTemplate)
+	survey.GET("/:survey", c.Owned)
+	survey.PUT("/:survey", c.Replace)
+	survey.PATCH("/:survey", c.Update)
+	survey.DELETE("/:survey", c.Remove)
+	survey.GET("/:survey/moderation", c.Moderations)
+	survey.GET("/:survey/my-ballot", c.MyBallot)
+	survey.GET("/:survey/quotas", c.Quotas)
+	survey.POST("/:survey/invitations", c.CreateInvitations)
+	survey.GET("/:survey/invitations", c.ListInvitations)
+	survey.DELETE("/:survey/invitations/:invitation", c.RevokeInvitation)
+	survey.PUT("/:survey/draft", c.SaveDraft)
+	survey.GET("/:survey/draft", c.Draft)
+	survey.POST("/:survey/draft/submit", c.SubmitDraft)
+	survey.GET("/:survey/export/results", c.ExportOwnedResults)
+	survey.GET("/:survey/export/responses", c.ExportOwnedResponses)
+	survey.GET("/:survey/export/coded", c.ExportOwnedCodedResponses)
+	survey.GET("/:survey/export/codebook", c.OwnedCodebook)
+	survey.GET("/:survey/definition", c.ExportDefinition)
+	survey.POST("/:survey/clone", c.Clone)
+	survey.PUT("/:survey/series", c.LinkWave)
+	survey.DELETE("/:survey/series", c.UnlinkWave)
+	survey.POST("/:survey/template", c.SaveTemplate)
 
 	return survey
 }
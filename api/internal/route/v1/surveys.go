@@ -9,6 +9,11 @@ import (
 // Surveys route that does not require auth
 func SetupSurveysRoute(surveys *gin.RouterGroup, c controller.SurveyControllerInterface) *gin.RouterGroup {
 	surveys.GET("/voter-details/:choice", c.ChoiceVoters)
+	surveys.GET("/crosstab/:survey", c.Crosstab)
+	surveys.GET("/series/:survey", c.Series)
+	surveys.GET("/votes/:survey", c.Votes)
+	surveys.GET("/ballot-log/:survey", c.BallotLog)
+	surveys.GET("/receipt/:receipt", c.CheckReceipt)
 	surveys.GET("/info/:survey", c.Info)
 	surveys.GET("/list/active", c.ListActive)
 	surveys.GET("/list/results", c.ListResults)
@@ -4,6 +4,7 @@ import (
 	"dou-survey/internal/logger"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	"gorm.io/driver/sqlite"
@@ -68,14 +69,75 @@ func InitializeDB(logger logger.Logger) *DbStore {
 
 	db.AutoMigrate(
 		&model.Vote{},
+		&model.Response{},
+		&model.ReplacedVote{},
+		&model.DemographicTally{},
+		&model.BallotLogEntry{},
+		&model.EligibleValue{},
+		&model.Quota{},
+		&model.Invitation{},
+		&model.DraftBallot{},
+		&model.SurveyTemplate{},
+		&model.Tally{},
 		&model.Choice{},
+		&model.Condition{},
 		&model.Question{},
 		&model.Survey{},
+		&model.PrivacyRelease{},
+		&model.SurveyTransition{},
+		&model.Moderation{},
 		&model.Employee{},
 		&model.User{},
 	)
 
+	// tables that link voters to anonymous ballots keep no insertion order
+	unordered := []interface{}{&model.Participation{}, &model.Redemption{}, &model.PendingBallot{}}
+	if err = dropRowIDs(db, unordered...); err != nil {
+		logger.Fatalf(err.Error())
+		return nil
+	}
+	db.Set("gorm:table_options", "WITHOUT ROWID").AutoMigrate(unordered...)
+
 	return &DbStore{
 		db,
 	}
 }
+
+// dropRowIDs moves the rows of tables created with a rowid into tables without one,
+// a rowid follows the insertion order of the rows
+func dropRowIDs(db *gorm.DB, models ...interface{}) error {
+	for _, value := range models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(value); err != nil {
+			return err
+		}
+		table := stmt.Schema.Table
+
+		var schema string
+		if err := db.Raw("SELECT sql FROM sqlite_master WHERE type = 'table' AND name = ?", table).Scan(&schema).Error; err != nil {
+			return err
+		}
+		if schema == "" || strings.Contains(strings.ToUpper(schema), "WITHOUT ROWID") {
+			continue
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Migrator().RenameTable(table, table+"_rowid"); err != nil {
+				return err
+			}
+			if err := tx.Set("gorm:table_options", "WITHOUT ROWID").Migrator().CreateTable(value); err != nil {
+				return err
+			}
+			if err := tx.Exec(fmt.Sprintf("INSERT INTO `%s` SELECT * FROM `%s_rowid`", table, table)).Error; err != nil {
+				return err
+			}
+
+			return tx.Migrator().DropTable(table + "_rowid")
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}